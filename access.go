@@ -0,0 +1,76 @@
+package rbac
+
+import (
+	"strings"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// 角色访问授权
+type RoleAccess struct {
+	Role   string      // 角色名称
+	Grants []UriPolicy // 授予访问的政策，来自角色自身或继承的角色，Role为政策所属的角色
+	Super  bool        // 超级管理员，不经过授权政策直接放行，Grants为空
+}
+
+// 获取可以访问指定域中路径与方法的全部角色，即VerifyUriPolicy的反向查询
+// 是否放行由执行器判定，与验证请求的结果一致，按角色名称排序，在该域生效的超级管理员排在最后
+// 超级管理员被禁用或不在该域生效时按普通角色处理，只有自身政策授予访问时才会列出
+func (c *Casbin) ListAccessRoles(domain, path, method string) ([]RoleAccess, error) {
+	var (
+		e       *casbin.Enforcer
+		roles   []string
+		results []RoleAccess
+		err     error
+	)
+
+	if e, err = c.enforcer(); err != nil {
+		return nil, err
+	}
+	if roles, err = c.ListRoles(domain); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	pathMatch := c.PathMatch
+	c.mu.RUnlock()
+
+	for _, role := range roles {
+		var (
+			ok     bool
+			ps     []UriPolicy
+			grants []UriPolicy
+		)
+
+		if ok, err = e.Enforce(roleSubject(role), domain, path, method); err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		// 找出授予访问的允许政策
+		if ps, err = c.GetRolePermissions(role, domain); err != nil {
+			return nil, err
+		}
+		for i := range ps {
+			if ps[i].Effect == EffectDeny {
+				continue
+			}
+			if ok, err = matchUriPolicy(pathMatch, &ps[i], path, method); err != nil {
+				return nil, err
+			}
+			if ok {
+				grants = append(grants, ps[i])
+			}
+		}
+		results = append(results, RoleAccess{Role: role, Grants: grants})
+	}
+
+	// 在该域生效的超级管理员
+	for _, role := range c.SuperRole.roles() {
+		if c.SuperRole.match(roleSubject(role), domain) {
+			results = append(results, RoleAccess{Role: strings.TrimPrefix(role, rolePrefix), Super: true})
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,146 @@
+package rbac
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCasbin_ListAccessRoles(t *testing.T) {
+	var (
+		path  = filepath.Join(t.TempDir(), "policy.csv")
+		lines = "p, role::admin, default, /users, GET\n" +
+			"p, role::editor, default, /article, GET|POST\n" +
+			"p, role::reader, default, /article, GET\n" +
+			"p, role::guest, default, /article, GET\n" +
+			"p, role::guest, default, /article, GET, deny\n" +
+			"p, role::reader, www, /article, GET\n" +
+			"g, root, role::admin, default\n" +
+			"g, role::admin, role::editor, default\n" +
+			"g, role::editor, role::reader, default\n"
+		c   *Casbin
+		err error
+	)
+
+	assert.NoError(t, os.WriteFile(path, []byte(lines), 0644))
+	c = NewCasbin(path)
+
+	t.Run("TestCasbin_ListAccessRoles_Inherited", func(t *testing.T) {
+		roles, err := c.ListAccessRoles("default", "/article", "GET")
+		assert.NoError(t, err)
+		assert.Equal(t, []RoleAccess{
+			{Role: "admin", Grants: []UriPolicy{
+				{Role: "editor", Domain: "default", Path: "/article", Method: "GET|POST", Effect: EffectAllow},
+				{Role: "reader", Domain: "default", Path: "/article", Method: "GET", Effect: EffectAllow},
+			}},
+			{Role: "editor", Grants: []UriPolicy{
+				{Role: "editor", Domain: "default", Path: "/article", Method: "GET|POST", Effect: EffectAllow},
+				{Role: "reader", Domain: "default", Path: "/article", Method: "GET", Effect: EffectAllow},
+			}},
+			{Role: "reader", Grants: []UriPolicy{
+				{Role: "reader", Domain: "default", Path: "/article", Method: "GET", Effect: EffectAllow},
+			}},
+			{Role: "root", Super: true},
+		}, roles)
+
+		// 与验证请求的结果一致
+		for _, v := range roles {
+			assert.NoError(t, c.VerifyUriPolicy(&UriPolicy{Role: roleSubject(v.Role), Domain: "default", Path: "/article", Method: "GET"}))
+		}
+		assert.Error(t, c.VerifyUriPolicy(&UriPolicy{Role: roleSubject("guest"), Domain: "default", Path: "/article", Method: "GET"}))
+	})
+
+	t.Run("TestCasbin_ListAccessRoles_Method", func(t *testing.T) {
+		roles, err := c.ListAccessRoles("default", "/article", "POST")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"admin", "editor", "root"}, accessRoleNames(roles))
+	})
+
+	t.Run("TestCasbin_ListAccessRoles_SuperRole", func(t *testing.T) {
+		c.SuperRole = SuperRole{Roles: []string{"owner"}, Domains: []string{"www"}}
+		defer func() { c.SuperRole = SuperRole{} }()
+
+		roles, err := c.ListAccessRoles("default", "/users", "GET")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"admin"}, accessRoleNames(roles))
+
+		roles, err = c.ListAccessRoles("www", "/article", "GET")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"reader", "owner"}, accessRoleNames(roles))
+	})
+
+	t.Run("TestCasbin_ListAccessRoles_SuperRoleInactive", func(t *testing.T) {
+		var (
+			path = filepath.Join(t.TempDir(), "policy.csv")
+		)
+
+		assert.NoError(t, os.WriteFile(path, []byte("p, role::admin, default, /users, GET\n"+
+			"p, root, default, /article, GET\n"+
+			"g, root, role::admin, default\n"), 0644))
+		for _, sr := range []SuperRole{{Disabled: true}, {Domains: []string{"www"}}} {
+			sc := NewCasbin(path)
+			sc.SuperRole = sr
+
+			// 超级管理员未生效，只由自身政策授予访问
+			roles, err := sc.ListAccessRoles("default", "/article", "GET")
+			assert.NoError(t, err)
+			assert.Equal(t, []RoleAccess{
+				{Role: "root", Grants: []UriPolicy{
+					{Role: "root", Domain: "default", Path: "/article", Method: "GET", Effect: EffectAllow},
+				}},
+			}, roles)
+			assert.NoError(t, sc.VerifyUriPolicy(&UriPolicy{Role: "root", Domain: "default", Path: "/article", Method: "GET"}))
+
+			roles, err = sc.ListAccessRoles("default", "/users", "GET")
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"admin"}, accessRoleNames(roles))
+			assert.Error(t, sc.VerifyUriPolicy(&UriPolicy{Role: "root", Domain: "default", Path: "/users", Method: "GET"}))
+
+			names, err := sc.ListRoles("default")
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"admin", "root"}, names)
+		}
+	})
+
+	t.Run("TestCasbin_ListAccessRoles_PathMatch", func(t *testing.T) {
+		var (
+			path = filepath.Join(t.TempDir(), "policy.csv")
+			pc   = NewCasbin(path)
+		)
+
+		assert.NoError(t, os.WriteFile(path, []byte("p, role::reader, default, /users/:id, GET\n"+
+			"p, role::editor, default, /users/*, *\n"), 0644))
+		assert.NoError(t, pc.SetPathMatch(PathMatchKeyMatch2))
+		pc.SuperRole.Disabled = true
+
+		roles, err := pc.ListAccessRoles("default", "/users/1", "GET")
+		assert.NoError(t, err)
+		assert.Equal(t, []RoleAccess{
+			{Role: "editor", Grants: []UriPolicy{
+				{Role: "editor", Domain: "default", Path: "/users/*", Method: "*", Effect: EffectAllow},
+			}},
+			{Role: "reader", Grants: []UriPolicy{
+				{Role: "reader", Domain: "default", Path: "/users/:id", Method: "GET", Effect: EffectAllow},
+			}},
+		}, roles)
+	})
+
+	t.Run("TestCasbin_ListAccessRoles_PolicyFileInvalid", func(t *testing.T) {
+		_, err = NewCasbin("").ListAccessRoles("default", "/article", "GET")
+		assert.Error(t, err)
+		assert.Equal(t, ErrorPolicyFilePathInvalid, err.Error())
+	})
+}
+
+func accessRoleNames(roles []RoleAccess) []string {
+	var (
+		names []string
+	)
+
+	for _, v := range roles {
+		names = append(names, v.Role)
+	}
+	return names
+}
@@ -9,9 +9,14 @@
 package rbac
 
 import (
-	"bufio"
+	"bytes"
 	"errors"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/casbin/casbin/v2"
 	"github.com/casbin/casbin/v2/model"
@@ -21,72 +26,247 @@ import (
 
 type Casbin struct {
 	PolicyFilePath string
+	PolicyBackups  int // 保存政策文件时保留的备份数量，0为不备份
+	// Deprecated: 共享的域在多租户并发请求时不安全，验证请求不再使用该字段，
+	// 请使用 Settings.DefaultDomain、Token的签发域或 Rbac.VerifyDomainRequest
 	Domain         string
-	Enforcer       *casbin.Enforcer
+	Enforcer       *casbin.Enforcer // 当前使用的执行器，重新加载时整体替换
 	Adapter        persist.Adapter
+	PathMatch      string            // 路径匹配方式，默认为完全相等
+	SuperRole      SuperRole         // 超级管理员设置，修改后需要重新加载
+	PolicyLint     PolicyLintOptions // 保存与重新加载前的政策检查
+	OnWatcherError func(error)       // 同步其它节点的变更失败时的回调
+	watcher        Watcher           // 政策变更通知
+	fileAdapter    persist.Adapter   // 由PolicyFilePath创建的文件适配器，重新加载前检查政策文件
+	mu             sync.RWMutex      // 保护Enforcer与Adapter的替换
+	updateMu       sync.Mutex        // 串行化重新加载与政策变更
 }
 
+// 备份文件名中的时间格式
+const policyBackupTimeFormat = "20060102T150405.000000000"
+
 // 从字符串初始化模型
 var modelText = `
  [request_definition]
  r = sub, dom, obj, act
  
  [policy_definition]
- p = sub, dom, obj, act
+ p = sub, dom, obj, act, eft
  
  [role_definition]
  g = _, _, _
  
  [policy_effect]
- e = some(where (p.eft == allow))
+ e = some(where (p.eft == allow)) && !some(where (p.eft == deny))
  
  [matchers]
- m = g(r.sub, p.sub, r.dom) && r.dom == p.dom && r.obj == p.obj && r.act == p.act || r.sub == "root"
+ m = g(r.sub, p.sub, r.dom) && r.dom == p.dom && pathMatch(r.obj, p.obj) && methodMatch(r.act, p.act)
  `
 
-var insCasbin = &Casbin{}
-
+// 实例化Casbin组件，每次调用返回独立的实例
 func NewCasbin(policyFilePath string) *Casbin {
-	insCasbin.PolicyFilePath = policyFilePath
-	return insCasbin
+	return &Casbin{
+		PolicyFilePath: policyFilePath,
+	}
 }
 
+// 初始化执行器，加载模型与授权政策
 func (c *Casbin) Init() error {
+	return c.Reload()
+}
+
+// 重新加载授权政策
+// 新的执行器创建成功后才替换当前执行器，加载失败时继续使用原有的执行器
+func (c *Casbin) Reload() error {
+	c.updateMu.Lock()
+	defer c.updateMu.Unlock()
+
+	return c.load()
+}
+
+// 从适配器加载授权政策并替换执行器，调用方需持有updateMu
+func (c *Casbin) load() error {
 	var (
 		a   persist.Adapter
-		e   *casbin.Enforcer // Casbin执行器
-		m   model.Model      // Casbin认证模型
+		e   *casbin.Enforcer
 		err error
 	)
 
-	// 设置Adapter
+	if a, err = c.adapter(); err != nil {
+		return err
+	}
+	c.mu.RLock()
+	pathMatch := c.PathMatch
+	c.mu.RUnlock()
+	if e, err = newEnforcer(a, pathMatch, c.SuperRole.subject()); err != nil {
+		return err
+	}
+	// 首次加载不检查，升级前可以加载的政策文件仍然可以启动；重新加载时检查通过后才替换执行器
+	c.mu.RLock()
+	loaded := c.Enforcer != nil
+	c.mu.RUnlock()
+	if loaded {
+		if err = c.lintLoaded(a, e); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	c.Enforcer = e
+	c.mu.Unlock()
+
+	return nil
+}
+
+// 获取当前的执行器，尚未初始化时进行初始化
+func (c *Casbin) enforcer() (*casbin.Enforcer, error) {
+	c.mu.RLock()
+	e := c.Enforcer
+	c.mu.RUnlock()
+
+	if e != nil {
+		return e, nil
+	}
+	if err := c.Init(); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Enforcer, nil
+}
+
+// 获取适配器，未设置时使用政策文件创建默认的文件适配器
+func (c *Casbin) adapter() (persist.Adapter, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.Adapter == nil {
 		if c.PolicyFilePath == "" {
-			return errors.New(ErrorPolicyFilePathInvalid)
+			return nil, errors.New(ErrorPolicyFilePathInvalid)
 		}
-		a = fileadapter.NewAdapter(c.PolicyFilePath)
-		c.Adapter = a
+		c.Adapter = fileadapter.NewAdapter(c.PolicyFilePath)
+		c.fileAdapter = c.Adapter
 	}
+	return c.Adapter, nil
+}
+
+// 创建执行器，按路径匹配方式注册匹配函数
+func newEnforcer(a persist.Adapter, pathMatch, root string) (*casbin.Enforcer, error) {
+	var (
+		e   *casbin.Enforcer
+		m   model.Model // Casbin认证模型
+		err error
+	)
+
 	// 使用字符串获取 Casbin模型
 	if m, err = model.NewModelFromString(modelText); err != nil {
-		return err
+		return nil, err
 	}
 	// 获取 Casbin执行器
-	if e, err = casbin.NewEnforcer(m, a); err != nil {
-		return err
+	if e, err = casbin.NewEnforcer(m, &effectAdapter{a}); err != nil {
+		return nil, err
 	}
-	c.Adapter = a
-	c.Enforcer = e
+	if err = unlinkSuperRole(e, root); err != nil {
+		return nil, err
+	}
+	if err = addMatchFunctions(e, pathMatch); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// 使用已包含授权政策的模型创建执行器，不从适配器加载
+func newEnforcerWithModel(m model.Model, a persist.Adapter, pathMatch, root string) (*casbin.Enforcer, error) {
+	var (
+		e   *casbin.Enforcer
+		err error
+	)
+
+	if e, err = casbin.NewEnforcer(m); err != nil {
+		return nil, err
+	}
+	e.SetAdapter(&effectAdapter{a})
+	if err = e.BuildRoleLinks(); err != nil {
+		return nil, err
+	}
+	if err = unlinkSuperRole(e, root); err != nil {
+		return nil, err
+	}
+	if err = addMatchFunctions(e, pathMatch); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// 移除超级管理员(以及默认的 root)与顶级角色之间的继承关系
+// 没有父级角色的角色挂在超级管理员下只用于记录角色层级，不授予权限：
+// 超级管理员由 SuperRole 直接放行，关闭或不在生效的域时不会经角色关系获得任何权限
+func unlinkSuperRole(e *casbin.Enforcer, root string) error {
+	var (
+		rm   = e.GetRoleManager()
+		seen = make(map[string]bool)
+	)
+
+	for _, rule := range e.GetModel().GetPolicy("g", "g") {
+		key := strings.Join(rule, model.DefaultSep)
+		if !isTopLevelParent(rule[0], root) || seen[key] {
+			continue
+		}
+		seen[key] = true
+		if err := rm.DeleteLink(rule[0], rule[1], rule[2:]...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 注册路径与方法匹配函数
+func addMatchFunctions(e *casbin.Enforcer, pathMatch string) error {
+	if pathMatch == "" {
+		pathMatch = PathMatchExact
+	}
+	pathMatchFunc, ok := pathMatchFuncs[pathMatch]
+	if !ok {
+		return errors.New(ErrorCasbinPathMatchInvalid)
+	}
+	e.AddFunction("pathMatch", pathMatchFunc)
+	e.AddFunction("methodMatch", methodMatchFunc)
+
+	return nil
+}
+
+// 设置路径匹配方式，下次验证时使用新的匹配方式重新加载
+func (c *Casbin) SetPathMatch(pathMatch string) error {
+	if _, ok := pathMatchFuncs[pathMatch]; pathMatch != "" && !ok {
+		return errors.New(ErrorCasbinPathMatchInvalid)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.PathMatch = pathMatch
+	c.Enforcer = nil
 
 	return nil
 }
 
-// 设置适配器
+// 设置适配器，下次验证时使用新的适配器重新加载授权政策
 func (c *Casbin) SetAdapter(a persist.Adapter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.Adapter = a
+	c.Enforcer = nil
 }
 
 // 设置域
+//
+// Deprecated: 共享的域在多租户并发请求时不安全，验证请求不再使用设置的域，
+// 请使用 Settings.DefaultDomain、Token的签发域或 Rbac.VerifyDomainRequest
 func (c *Casbin) SetDomain(domain string) {
 	c.Domain = domain
 }
@@ -94,11 +274,19 @@ func (c *Casbin) SetDomain(domain string) {
 // 检测Policy
 func (c *Casbin) VerifyUriPolicy(p *UriPolicy) error {
 	var (
+		e   *casbin.Enforcer
 		err error
 		ok  bool
 	)
 
-	ok, err = c.Enforcer.Enforce(p.Role, p.Domain, p.Path, p.Method)
+	// 超级管理员拥有全部权限，不受拒绝政策影响
+	if c.SuperRole.bypass(p) {
+		return nil
+	}
+	if e, err = c.enforcer(); err != nil {
+		return err
+	}
+	ok, err = e.Enforce(p.Role, p.Domain, p.Path, p.Method)
 	if err != nil {
 		return err
 	}
@@ -110,39 +298,192 @@ func (c *Casbin) VerifyUriPolicy(p *UriPolicy) error {
 }
 
 // 更新Policy.csv文件
+// 先写入临时文件并落盘，再原子替换政策文件，写入过程中崩溃不会留下残缺的政策文件；
+// 文件不存在时自动创建，设置了PolicyBackups时保留替换前的备份；保存后通知其它节点重新加载，
+// 通知失败时返回 *WatcherNotifyError，此时政策已经保存并生效
 func (c *Casbin) SaveAllPolicyCsv(ups []UriPolicy, rps []RolePolicy) error {
+	if err := c.savePolicyCsv(ups, rps); err != nil {
+		return err
+	}
+	return c.notifyWatcher(watcherOpReload, "", nil)
+}
+
+// 写入政策文件并重新加载
+func (c *Casbin) savePolicyCsv(ups []UriPolicy, rps []RolePolicy) error {
+	c.updateMu.Lock()
+	defer c.updateMu.Unlock()
+
+	if err := c.writePolicyFile(ups, rps); err != nil {
+		return err
+	}
+
+	// 已加载的执行器同步最新的授权政策
+	c.mu.RLock()
+	loaded := c.Enforcer != nil
+	c.mu.RUnlock()
+	if loaded {
+		return c.load()
+	}
+
+	return nil
+}
+
+// 检查并写入政策文件，调用方需持有updateMu
+// 政策按CSV规则格式化，写入前备份原有的文件，再原子替换
+func (c *Casbin) writePolicyFile(ups []UriPolicy, rps []RolePolicy) error {
 	var (
 		filePath = c.PolicyFilePath
-		file     *os.File
-		writer   *bufio.Writer
+		buf      bytes.Buffer
+		perm     os.FileMode = 0644
+		info     os.FileInfo
 		err      error
 	)
 
 	if filePath == "" {
 		return errors.New(ErrorPolicyFilePathInvalid)
 	}
-	// 获取文件句柄
-	file, err = os.OpenFile(filePath, os.O_WRONLY|os.O_TRUNC, 0666)
-	if err != nil {
-		return err
+	// 检查政策，存在错误时不写入
+	if !c.PolicyLint.Disabled {
+		if err = lintFailed(LintPolicies(ups, rps, c.lintOptions()), c.PolicyLint.Strict); err != nil {
+			return err
+		}
 	}
-	defer file.Close()
 
-	// 写入文件
-	writer = bufio.NewWriter(file)
+	// 格式化政策
 	for _, v := range ups {
-		writer.WriteString(v.FormatLine())
-		writer.WriteString("\n")
+		buf.WriteString(v.FormatLine())
+		buf.WriteString("\n")
 	}
 	for _, v := range rps {
-		writer.WriteString(v.FormatLine())
-		writer.WriteString("\n")
+		buf.WriteString(v.formatLine(c.SuperRole.subject()))
+		buf.WriteString("\n")
+	}
+
+	// 备份原有的政策文件，沿用原有的文件权限
+	if info, err = os.Stat(filePath); err == nil {
+		perm = info.Mode().Perm()
+		if err = c.backupPolicyFile(); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	return writeFileAtomic(filePath, buf.Bytes(), perm)
+}
+
+// 获取政策检查选项，未设置路径匹配方式与超级管理员时使用当前的设置
+func (c *Casbin) lintOptions() PolicyLintOptions {
+	var (
+		opts = c.PolicyLint
+	)
+
+	if opts.PathMatch == "" {
+		c.mu.RLock()
+		opts.PathMatch = c.PathMatch
+		c.mu.RUnlock()
+	}
+	if opts.SuperRole == "" {
+		opts.SuperRole = c.SuperRole.roles()[0]
+	}
+	return opts
+}
+
+// 检查加载的政策，文件适配器检查政策文件以报告行号，其它适配器按加载的顺序检查
+func (c *Casbin) lintLoaded(a persist.Adapter, e *casbin.Enforcer) error {
+	var (
+		issues []PolicyIssue
+		ups    []UriPolicy
+		rps    []RolePolicy
+		err    error
+	)
+
+	if c.PolicyLint.Disabled {
+		return nil
+	}
+	c.mu.RLock()
+	fromFile := a == c.fileAdapter
+	c.mu.RUnlock()
+
+	if fromFile {
+		if issues, err = LintPolicyFile(c.PolicyFilePath, c.lintOptions()); err != nil {
+			return err
+		}
+	} else {
+		for _, rule := range e.GetModel().GetPolicy("p", "p") {
+			ups = append(ups, uriPolicyFromRule(rule))
+		}
+		for _, rule := range e.GetModel().GetPolicy("g", "g") {
+			rps = append(rps, rolePolicyFromRule(rule, c.SuperRole.subject()))
+		}
+		issues = LintPolicies(ups, rps, c.lintOptions())
+	}
+
+	return lintFailed(issues, c.PolicyLint.Strict)
+}
+
+// 检查政策集合，使用Casbin的检查选项
+func (c *Casbin) LintPolicies(ups []UriPolicy, rps []RolePolicy) []PolicyIssue {
+	return LintPolicies(ups, rps, c.lintOptions())
+}
+
+// 备份政策文件，备份文件名为 <政策文件>.<时间>.bak，只保留最新的PolicyBackups个备份
+func (c *Casbin) backupPolicyFile() error {
+	var (
+		data    []byte
+		backups []string
+		err     error
+	)
+
+	if c.PolicyBackups <= 0 {
+		return nil
+	}
+	if data, err = os.ReadFile(c.PolicyFilePath); err != nil {
+		return err
+	}
+	backup := c.PolicyFilePath + "." + time.Now().Format(policyBackupTimeFormat) + ".bak"
+	if err = writeFileAtomic(backup, data, 0600); err != nil {
+		return err
+	}
+
+	// 时间格式定长，按文件名排序即按时间排序
+	if backups, err = policyBackups(c.PolicyFilePath); err != nil {
+		return err
+	}
+	for len(backups) > c.PolicyBackups {
+		if err = os.Remove(backups[0]); err != nil {
+			return err
+		}
+		backups = backups[1:]
 	}
-	writer.Flush()
 
 	return nil
 }
 
+// 获取政策文件的备份，按时间从旧到新排序
+func policyBackups(path string) ([]string, error) {
+	var (
+		dir     = filepath.Dir(path)
+		prefix  = filepath.Base(path) + "."
+		backups []string
+	)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, prefix) && strings.HasSuffix(name, ".bak") &&
+			len(name) == len(prefix)+len(policyBackupTimeFormat)+len(".bak") {
+			backups = append(backups, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(backups)
+
+	return backups, nil
+}
+
 func (c *Casbin) Demo() {
 
 }
@@ -1,5 +1,9 @@
 package rbac
 
+import (
+	"fmt"
+)
+
 func ExampleCasbin_SaveAllPolicyCsv() {
 	var (
 		uriPolicys = []UriPolicy{
@@ -45,12 +49,34 @@ func ExampleCasbin_SaveAllPolicyCsv() {
 				Path:   "/article",
 				Method: "GET",
 			},
+			{
+				Domain: "default",
+				Role:   "userRole1",
+				Path:   "/user",
+				Method: "GET",
+			},
+			{
+				Domain: "default",
+				Role:   "userRole1",
+				Path:   "/user",
+				Method: "DELETE",
+			},
+			{
+				Domain: "default",
+				Role:   "userRole2",
+				Path:   "/users",
+				Method: "GET",
+			},
 		}
 		rolePolicys = []RolePolicy{
 			{
 				Role:   "admin1",
 				Domain: "manager",
 			},
+			{
+				Role:   "admin1",
+				Domain: "www",
+			},
 			{
 				ParentRole: "admin1",
 				Role:       "admin2",
@@ -61,6 +87,14 @@ func ExampleCasbin_SaveAllPolicyCsv() {
 				Role:       "userGroup1",
 				Domain:     "www",
 			},
+			{
+				Role:   "userRole1",
+				Domain: "default",
+			},
+			{
+				Role:   "userRole2",
+				Domain: "default",
+			},
 		}
 		sets = Settings{
 			TokenSignKey:   []byte("gVoiG1fbXf65osbjfi33MZre"),
@@ -83,34 +117,50 @@ func ExampleCasbin_SaveAllPolicyCsv() {
 	//
 }
 
-// func ExampleCasbin_Demo() {
-// 	var (
-// 		sets = Settings{
-// 			TokenSignKey:   []byte("gVoiG1fbXf65osbjfi33MZre"),
-// 			TokenIssuer:    "lgcgo.com",
-// 			PolicyFilePath: "examples/policy.csv",
-// 		}
-// 		res [][]string
-// 		r   *Rbac
-// 	)
-// 	r, _ = New(sets)
-// 	r.Casbin.Init()
-// 	// res = r.Casbin.Enforcer.GetAllSubjects()
-// 	// res = r.Casbin.Enforcer.GetAllObjects()
-// 	// res = r.Casbin.Enforcer.GetAllNamedActions("p")
-// 	// res = r.Casbin.Enforcer.GetAllNamedObjects("p")
-// 	// res = r.Casbin.Enforcer.GetAllRoles()
-// 	// res = r.Casbin.Enforcer.GetPolicy() // 只会获取p类型政策，不会获取角色关系
-// 	// res, _ = r.Casbin.Enforcer.GetUsersForRole("role::admin2", "manager") // 必须指定domain，只能获取直属父级
-// 	// res, _ = r.Casbin.Enforcer.GetAllDomains()
-// 	// res, _ = r.Casbin.Enforcer.GetDomainsForUser("root")
+func ExampleCasbin_GetRoleDescendants() {
+	var (
+		c     = NewCasbin("examples/policy.csv")
+		roles []string
+		err   error
+	)
 
-// 	res = r.Casbin.Enforcer.GetPermissionsForUser("role::admin1")
-// 	for _, v := range res {
-// 		fmt.Println(v)
-// 	}
-// 	fmt.Println(res)
+	// 哪些角色的权限被 admin1 继承
+	if roles, err = c.GetRoleDescendants("admin1", "manager"); err != nil {
+		panic(err)
+	}
+	fmt.Println(roles)
+	// 哪些角色继承了 admin2 的权限
+	if roles, err = c.GetRoleAncestors("admin2", "manager"); err != nil {
+		panic(err)
+	}
+	fmt.Println(roles)
+	if roles, err = c.ListRoles("www"); err != nil {
+		panic(err)
+	}
+	fmt.Println(roles)
+
+	// Output:
+	// [admin2]
+	// [admin1]
+	// [admin1 userGroup1]
+}
 
-// 	// Output:
-// 	//
-// }
+func ExampleCasbin_GetRolePermissions() {
+	var (
+		c   = NewCasbin("examples/policy.csv")
+		ps  []UriPolicy
+		err error
+	)
+
+	// admin1 的有效权限包含继承自 userGroup1 的政策
+	if ps, err = c.GetRolePermissions("admin1", "www"); err != nil {
+		panic(err)
+	}
+	for _, v := range ps {
+		fmt.Println(v.Role, v.Path, v.Method)
+	}
+
+	// Output:
+	// admin1 /article GET
+	// userGroup1 /article GET
+}
@@ -0,0 +1,244 @@
+package rbac
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// 添加资源访问政策
+func (c *Casbin) AddUriPolicy(p *UriPolicy) error {
+	return c.AddUriPolicies([]UriPolicy{*p})
+}
+
+// 批量添加资源访问政策，任一政策已存在时全部不添加
+func (c *Casbin) AddUriPolicies(ps []UriPolicy) error {
+	return c.change(watcherOpAdd, "p", uriPolicyRules(ps))
+}
+
+// 移除资源访问政策
+func (c *Casbin) RemoveUriPolicy(p *UriPolicy) error {
+	return c.RemoveUriPolicies([]UriPolicy{*p})
+}
+
+// 批量移除资源访问政策，任一政策不存在时全部不移除
+func (c *Casbin) RemoveUriPolicies(ps []UriPolicy) error {
+	return c.change(watcherOpRemove, "p", uriPolicyRules(ps))
+}
+
+// 添加角色关系政策
+func (c *Casbin) AddRolePolicy(p *RolePolicy) error {
+	return c.AddRolePolicies([]RolePolicy{*p})
+}
+
+// 批量添加角色关系政策，任一政策已存在时全部不添加
+func (c *Casbin) AddRolePolicies(ps []RolePolicy) error {
+	return c.change(watcherOpAdd, "g", c.rolePolicyRules(ps))
+}
+
+// 移除角色关系政策
+func (c *Casbin) RemoveRolePolicy(p *RolePolicy) error {
+	return c.RemoveRolePolicies([]RolePolicy{*p})
+}
+
+// 批量移除角色关系政策，任一政策不存在时全部不移除
+func (c *Casbin) RemoveRolePolicies(ps []RolePolicy) error {
+	return c.change(watcherOpRemove, "g", c.rolePolicyRules(ps))
+}
+
+// 查询资源访问政策，过滤条件中为空的字段不参与过滤，过滤条件为nil时返回全部
+func (c *Casbin) ListUriPolicies(filter *UriPolicy) ([]UriPolicy, error) {
+	var (
+		e   *casbin.Enforcer
+		ps  []UriPolicy
+		err error
+	)
+
+	if e, err = c.enforcer(); err != nil {
+		return nil, err
+	}
+	for _, rule := range e.GetModel().GetPolicy("p", "p") {
+		p := uriPolicyFromRule(rule)
+		if filter == nil || filter.match(&p) {
+			ps = append(ps, p)
+		}
+	}
+
+	return ps, nil
+}
+
+// 查询角色关系政策，过滤条件中为空的字段不参与过滤，过滤条件为nil时返回全部
+func (c *Casbin) ListRolePolicies(filter *RolePolicy) ([]RolePolicy, error) {
+	var (
+		e   *casbin.Enforcer
+		ps  []RolePolicy
+		err error
+	)
+
+	if e, err = c.enforcer(); err != nil {
+		return nil, err
+	}
+	for _, rule := range e.GetModel().GetPolicy("g", "g") {
+		p := rolePolicyFromRule(rule, c.SuperRole.subject())
+		if filter == nil || filter.match(&p) {
+			ps = append(ps, p)
+		}
+	}
+
+	return ps, nil
+}
+
+// 变更授权政策，保存后通知其它节点
+// 通知失败时返回 *WatcherNotifyError，此时变更已经生效并保存
+func (c *Casbin) change(op, sec string, rules [][]string) error {
+	if err := c.update(op, sec, rules, true); err != nil {
+		return err
+	}
+	return c.notifyWatcher(op, sec, rules)
+}
+
+// 变更授权政策
+// 在当前政策的副本上执行变更，创建新的执行器并通过适配器保存后才替换当前执行器，
+// 任一步骤失败时当前政策保持不变；save为false时只变更执行器，用于同步其它节点已保存的变更
+func (c *Casbin) update(op, sec string, rules [][]string, save bool) error {
+	var (
+		a   persist.Adapter
+		e   *casbin.Enforcer
+		m   model.Model
+		err error
+	)
+
+	c.updateMu.Lock()
+	defer c.updateMu.Unlock()
+
+	c.mu.RLock()
+	e = c.Enforcer
+	pathMatch := c.PathMatch
+	c.mu.RUnlock()
+	// 尚未加载时先加载
+	if e == nil {
+		if err = c.load(); err != nil {
+			return err
+		}
+		c.mu.RLock()
+		e = c.Enforcer
+		c.mu.RUnlock()
+	}
+	if a, err = c.adapter(); err != nil {
+		return err
+	}
+
+	m = e.GetModel().Copy()
+	switch op {
+	case watcherOpAdd:
+		err = addRules(m, sec, rules)
+	case watcherOpRemove:
+		err = removeRules(m, sec, rules)
+	}
+	if err != nil {
+		return err
+	}
+	if e, err = newEnforcerWithModel(m, a, pathMatch, c.SuperRole.subject()); err != nil {
+		return err
+	}
+	// 保存全部政策，兼容不支持单条写入的适配器
+	if save {
+		if err = c.savePolicy(a, m); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	c.Enforcer = e
+	c.mu.Unlock()
+
+	return nil
+}
+
+// 保存模型中的全部政策，调用方需持有updateMu
+// 使用政策文件时与SaveAllPolicyCsv一样检查、备份并原子写入，其它适配器调用SavePolicy
+func (c *Casbin) savePolicy(a persist.Adapter, m model.Model) error {
+	var (
+		ups []UriPolicy
+		rps []RolePolicy
+	)
+
+	c.mu.RLock()
+	fromFile := a == c.fileAdapter
+	c.mu.RUnlock()
+	if !fromFile {
+		return a.SavePolicy(m)
+	}
+
+	for _, rule := range m.GetPolicy("p", "p") {
+		ups = append(ups, uriPolicyFromRule(rule))
+	}
+	for _, rule := range m.GetPolicy("g", "g") {
+		rps = append(rps, rolePolicyFromRule(rule, c.SuperRole.subject()))
+	}
+	return c.writePolicyFile(ups, rps)
+}
+
+// 添加政策规则，任一规则已存在时返回错误
+func addRules(m model.Model, sec string, rules [][]string) error {
+	var (
+		seen = make(map[string]bool)
+	)
+
+	for _, rule := range rules {
+		key := strings.Join(rule, model.DefaultSep)
+		if seen[key] || m.HasPolicy(sec, sec, rule) {
+			return errors.New(ErrorCasbinPolicyExists)
+		}
+		seen[key] = true
+	}
+	m.AddPolicies(sec, sec, rules)
+
+	return nil
+}
+
+// 移除政策规则，任一规则不存在时返回错误
+func removeRules(m model.Model, sec string, rules [][]string) error {
+	var (
+		seen = make(map[string]bool)
+	)
+
+	for _, rule := range rules {
+		key := strings.Join(rule, model.DefaultSep)
+		if seen[key] || !m.HasPolicy(sec, sec, rule) {
+			return errors.New(ErrorCasbinPolicyNotFound)
+		}
+		seen[key] = true
+	}
+	m.RemovePolicies(sec, sec, rules)
+
+	return nil
+}
+
+// 资源访问政策转换为政策规则
+func uriPolicyRules(ps []UriPolicy) [][]string {
+	var (
+		rules = make([][]string, 0, len(ps))
+	)
+
+	for _, p := range ps {
+		rules = append(rules, p.rule())
+	}
+	return rules
+}
+
+// 角色关系政策转换为政策规则，没有父级角色时挂在超级管理员下
+func (c *Casbin) rolePolicyRules(ps []RolePolicy) [][]string {
+	var (
+		root  = c.SuperRole.subject()
+		rules = make([][]string, 0, len(ps))
+	)
+
+	for _, p := range ps {
+		rules = append(rules, p.rule(root))
+	}
+	return rules
+}
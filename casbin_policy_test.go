@@ -0,0 +1,146 @@
+package rbac
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCasbin_PolicyCRUD(t *testing.T) {
+	var (
+		path = filepath.Join(t.TempDir(), "policy.csv")
+		sets = Settings{
+			TokenSignKey:   []byte("gVoiG1fbXf65osbjfi33MZre"),
+			PolicyFilePath: path,
+		}
+		reader = UriPolicy{Role: "reader", Domain: "default", Path: "/article", Method: "GET"}
+		writer = UriPolicy{Role: "writer", Domain: "default", Path: "/article", Method: "POST"}
+		r      *Rbac
+		err    error
+	)
+
+	assert.NoError(t, os.WriteFile(path, []byte("p, role::reader, default, /users, GET\n"), 0644))
+	r, err = New(sets)
+	assert.NoError(t, err)
+
+	t.Run("TestCasbin_AddUriPolicy", func(t *testing.T) {
+		assert.Error(t, r.VerifyRequest("/article", "GET", "reader"))
+		assert.NoError(t, r.Casbin.AddUriPolicy(&reader))
+		// 立即生效
+		assert.NoError(t, r.VerifyRequest("/article", "GET", "reader"))
+
+		err = r.Casbin.AddUriPolicy(&reader)
+		assert.Error(t, err)
+		assert.Equal(t, ErrorCasbinPolicyExists, err.Error())
+	})
+
+	t.Run("TestCasbin_AddUriPolicies_AllOrNothing", func(t *testing.T) {
+		err = r.Casbin.AddUriPolicies([]UriPolicy{writer, reader})
+		assert.Error(t, err)
+		assert.Error(t, r.VerifyRequest("/article", "POST", "writer"))
+
+		assert.NoError(t, r.Casbin.AddUriPolicies([]UriPolicy{writer}))
+		assert.NoError(t, r.VerifyRequest("/article", "POST", "writer"))
+	})
+
+	t.Run("TestCasbin_AddRolePolicy", func(t *testing.T) {
+		// editor 继承 reader 与 writer 的权限
+		assert.NoError(t, r.Casbin.AddRolePolicies([]RolePolicy{
+			{ParentRole: "editor", Role: "reader", Domain: "default"},
+			{ParentRole: "editor", Role: "writer", Domain: "default"},
+			{Role: "editor", Domain: "default"},
+		}))
+		assert.NoError(t, r.VerifyRequest("/article", "GET", "editor"))
+		assert.NoError(t, r.VerifyRequest("/article", "POST", "editor"))
+
+		ps, err := r.Casbin.ListRolePolicies(&RolePolicy{ParentRole: "editor"})
+		assert.NoError(t, err)
+		assert.Len(t, ps, 2)
+		ps, _ = r.Casbin.ListRolePolicies(&RolePolicy{Role: "editor"})
+		assert.Equal(t, []RolePolicy{{Role: "editor", Domain: "default"}}, ps)
+	})
+
+	t.Run("TestCasbin_ListUriPolicies", func(t *testing.T) {
+		ps, err := r.Casbin.ListUriPolicies(&UriPolicy{Role: "reader"})
+		assert.NoError(t, err)
+		assert.Len(t, ps, 2)
+		ps, _ = r.Casbin.ListUriPolicies(&UriPolicy{Path: "/article", Method: "POST"})
+		assert.Equal(t, []UriPolicy{{Role: "writer", Domain: "default", Path: "/article", Method: "POST", Effect: EffectAllow}}, ps)
+		ps, _ = r.Casbin.ListUriPolicies(nil)
+		assert.Len(t, ps, 3)
+	})
+
+	t.Run("TestCasbin_RemovePolicies_AllOrNothing", func(t *testing.T) {
+		missing := UriPolicy{Role: "reader", Domain: "default", Path: "/missing", Method: "GET"}
+		err = r.Casbin.RemoveUriPolicies([]UriPolicy{reader, missing})
+		assert.Error(t, err)
+		assert.Equal(t, ErrorCasbinPolicyNotFound, err.Error())
+		assert.NoError(t, r.VerifyRequest("/article", "GET", "reader"))
+
+		assert.NoError(t, r.Casbin.RemoveUriPolicy(&reader))
+		assert.Error(t, r.VerifyRequest("/article", "GET", "reader"))
+		assert.NoError(t, r.Casbin.RemoveRolePolicy(&RolePolicy{ParentRole: "editor", Role: "writer", Domain: "default"}))
+		assert.Error(t, r.VerifyRequest("/article", "POST", "editor"))
+	})
+
+	t.Run("TestCasbin_PolicyPersisted", func(t *testing.T) {
+		r, err := New(sets)
+		assert.NoError(t, err)
+		assert.NoError(t, r.VerifyRequest("/users", "GET", "reader"))
+		assert.NoError(t, r.VerifyRequest("/article", "POST", "writer"))
+		assert.Error(t, r.VerifyRequest("/article", "GET", "reader"))
+		assert.Error(t, r.VerifyRequest("/article", "POST", "editor"))
+	})
+}
+
+func TestCasbin_PolicyCRUD_PolicyFile(t *testing.T) {
+	var (
+		dir  = t.TempDir()
+		path = filepath.Join(dir, "policy.csv")
+		c    = NewCasbin(path)
+		p    = UriPolicy{Role: "reader", Domain: "default", Path: "^/v{1,2}/users$", Method: "GET"}
+	)
+
+	assert.NoError(t, os.WriteFile(path, []byte("p, role::reader, default, ^/articles$, GET\n"), 0644))
+	assert.NoError(t, c.SetPathMatch(PathMatchRegex))
+	c.PolicyBackups = 2
+
+	t.Run("TestCasbin_AddUriPolicy_QuotedPath", func(t *testing.T) {
+		assert.NoError(t, c.AddUriPolicy(&p))
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), `"^/v{1,2}/users$"`)
+
+		// 包含逗号的路径重新加载后保持不变
+		assert.NoError(t, c.Reload())
+		ps, err := c.ListUriPolicies(&UriPolicy{Role: "reader"})
+		assert.NoError(t, err)
+		assert.Len(t, ps, 2)
+		assert.Equal(t, p.Path, ps[1].Path)
+		assert.NoError(t, c.VerifyUriPolicy(&UriPolicy{Role: roleSubject("reader"), Domain: "default", Path: "/vv/users", Method: "GET"}))
+	})
+
+	t.Run("TestCasbin_AddUriPolicy_Backup", func(t *testing.T) {
+		backups, err := policyBackups(path)
+		assert.NoError(t, err)
+		assert.Len(t, backups, 1)
+	})
+
+	t.Run("TestCasbin_AddUriPolicy_Lint", func(t *testing.T) {
+		var (
+			lintErr *PolicyLintError
+		)
+
+		// 检查失败时不写入也不生效
+		err := c.AddUriPolicy(&UriPolicy{Role: "reader", Domain: "default", Path: "^/v[$", Method: "GET"})
+		assert.True(t, errors.As(err, &lintErr))
+		ps, err := c.ListUriPolicies(nil)
+		assert.NoError(t, err)
+		assert.Len(t, ps, 2)
+		_, _, err = ParsePolicyFile(path)
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,55 @@
+package rbac
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCasbin_SaveAllPolicyCsv(t *testing.T) {
+	var (
+		dir  = t.TempDir()
+		path = filepath.Join(dir, "policy.csv")
+		ups  = []UriPolicy{{Role: "reader", Domain: "default", Path: "/article", Method: "GET"}}
+		rps  = []RolePolicy{{Role: "reader", Domain: "default"}}
+		c    = NewCasbin(path)
+	)
+
+	t.Run("TestCasbin_SaveAllPolicyCsv_Create", func(t *testing.T) {
+		assert.NoError(t, c.SaveAllPolicyCsv(ups, rps))
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "p, role::reader, default, /article, GET\ng, root, role::reader, default\n", string(data))
+	})
+
+	t.Run("TestCasbin_SaveAllPolicyCsv_Reload", func(t *testing.T) {
+		assert.NoError(t, c.Init())
+		assert.Error(t, c.VerifyUriPolicy(&UriPolicy{Role: "role::reader", Domain: "default", Path: "/article", Method: "POST"}))
+
+		ups = append(ups, UriPolicy{Role: "reader", Domain: "default", Path: "/article", Method: "POST"})
+		assert.NoError(t, c.SaveAllPolicyCsv(ups, rps))
+		assert.NoError(t, c.VerifyUriPolicy(&UriPolicy{Role: "role::reader", Domain: "default", Path: "/article", Method: "POST"}))
+	})
+
+	t.Run("TestCasbin_SaveAllPolicyCsv_Backups", func(t *testing.T) {
+		c.PolicyBackups = 2
+		defer func() { c.PolicyBackups = 0 }()
+
+		for i := 0; i < 4; i++ {
+			assert.NoError(t, c.SaveAllPolicyCsv(ups, rps))
+		}
+		backups, err := policyBackups(path)
+		assert.NoError(t, err)
+		assert.Len(t, backups, 2)
+		data, _ := os.ReadFile(backups[1])
+		current, _ := os.ReadFile(path)
+		assert.Equal(t, current, data)
+	})
+
+	t.Run("TestCasbin_SaveAllPolicyCsv_WriteFailed", func(t *testing.T) {
+		c := NewCasbin(filepath.Join(dir, "missing", "policy.csv"))
+		assert.Error(t, c.SaveAllPolicyCsv(ups, rps))
+	})
+}
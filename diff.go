@@ -0,0 +1,332 @@
+package rbac
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// 政策差异
+type PolicyDiff struct {
+	AddedUriPolicies     []UriPolicy        // 新增的资源访问政策
+	RemovedUriPolicies   []UriPolicy        // 移除的资源访问政策
+	ModifiedUriPolicies  []UriPolicyChange  // 修改的资源访问政策，角色、域与路径相同
+	AddedRolePolicies    []RolePolicy       // 新增的角色关系政策
+	RemovedRolePolicies  []RolePolicy       // 移除的角色关系政策
+	ModifiedRolePolicies []RolePolicyChange // 修改的角色关系政策，角色与域相同
+	Impacts              []PolicyImpact     // 角色有效权限的变化，按域与角色排序
+}
+
+// 资源访问政策的修改
+type UriPolicyChange struct {
+	Old UriPolicy // 修改前
+	New UriPolicy // 修改后
+}
+
+// 角色关系政策的修改
+type RolePolicyChange struct {
+	Old RolePolicy // 修改前
+	New RolePolicy // 修改后
+}
+
+// 角色有效权限的变化，有效权限包含继承自后代角色的政策
+// 政策的Role为政策所属的角色，Effect为 deny 的政策表示拒绝访问
+type PolicyImpact struct {
+	Role   string      // 角色
+	Domain string      // 域
+	Gained []UriPolicy // 生效后新增的有效政策
+	Lost   []UriPolicy // 生效后失去的有效政策
+}
+
+// 判断是否没有差异
+func (d *PolicyDiff) Empty() bool {
+	return len(d.AddedUriPolicies) == 0 && len(d.RemovedUriPolicies) == 0 && len(d.ModifiedUriPolicies) == 0 &&
+		len(d.AddedRolePolicies) == 0 && len(d.RemovedRolePolicies) == 0 && len(d.ModifiedRolePolicies) == 0
+}
+
+// 对比当前加载的授权政策与新的授权政策，用于SaveAllPolicyCsv前预览变更
+func (c *Casbin) DiffPolicies(ups []UriPolicy, rps []RolePolicy) (*PolicyDiff, error) {
+	var (
+		e      *casbin.Enforcer
+		oldUps []UriPolicy
+		oldRps []RolePolicy
+		err    error
+	)
+
+	if e, err = c.enforcer(); err != nil {
+		return nil, err
+	}
+	for _, rule := range e.GetModel().GetPolicy("p", "p") {
+		oldUps = append(oldUps, uriPolicyFromRule(rule))
+	}
+	for _, rule := range e.GetModel().GetPolicy("g", "g") {
+		oldRps = append(oldRps, rolePolicyFromRule(rule, c.SuperRole.subject()))
+	}
+
+	return DiffPolicies(oldUps, oldRps, ups, rps), nil
+}
+
+// 对比两组授权政策，未设置效果的资源访问政策视为 allow
+func DiffPolicies(oldUps []UriPolicy, oldRps []RolePolicy, newUps []UriPolicy, newRps []RolePolicy) *PolicyDiff {
+	var (
+		d = &PolicyDiff{}
+	)
+
+	// 资源访问政策
+	removed, added := diffRules(uriPolicyKeys(oldUps), uriPolicyKeys(newUps))
+	for _, i := range removed {
+		d.RemovedUriPolicies = append(d.RemovedUriPolicies, oldUps[i])
+	}
+	for _, i := range added {
+		d.AddedUriPolicies = append(d.AddedUriPolicies, newUps[i])
+	}
+	d.RemovedUriPolicies, d.AddedUriPolicies, d.ModifiedUriPolicies = pairUriPolicies(d.RemovedUriPolicies, d.AddedUriPolicies)
+
+	// 角色关系政策
+	removed, added = diffRules(rolePolicyKeys(oldRps), rolePolicyKeys(newRps))
+	for _, i := range removed {
+		d.RemovedRolePolicies = append(d.RemovedRolePolicies, oldRps[i])
+	}
+	for _, i := range added {
+		d.AddedRolePolicies = append(d.AddedRolePolicies, newRps[i])
+	}
+	d.RemovedRolePolicies, d.AddedRolePolicies, d.ModifiedRolePolicies = pairRolePolicies(d.RemovedRolePolicies, d.AddedRolePolicies)
+
+	d.Impacts = diffImpacts(oldUps, oldRps, newUps, newRps)
+
+	return d
+}
+
+// 对比规则，返回移除与新增的规则下标
+func diffRules(olds, news []string) ([]int, []int) {
+	var (
+		oldSet  = make(map[string]bool)
+		newSet  = make(map[string]bool)
+		removed []int
+		added   []int
+	)
+
+	for _, v := range olds {
+		oldSet[v] = true
+	}
+	for i, v := range news {
+		if !oldSet[v] && !newSet[v] {
+			added = append(added, i)
+		}
+		newSet[v] = true
+	}
+	for i, v := range olds {
+		if !newSet[v] {
+			removed = append(removed, i)
+			// 重复的规则只报告一次
+			newSet[v] = true
+		}
+	}
+
+	return removed, added
+}
+
+// 资源访问政策的比较键
+func uriPolicyKeys(ups []UriPolicy) []string {
+	var (
+		keys []string
+	)
+
+	for i := range ups {
+		keys = append(keys, strings.Join(ups[i].rule(), "\x00"))
+	}
+	return keys
+}
+
+// 角色关系政策的比较键
+func rolePolicyKeys(rps []RolePolicy) []string {
+	var (
+		keys []string
+	)
+
+	for i := range rps {
+		// 没有父级角色时父级为空，与名称为 root 的父级角色区分
+		keys = append(keys, strings.Join(rps[i].rule(""), "\x00"))
+	}
+	return keys
+}
+
+// 角色、域与路径相同的移除与新增政策视为修改
+func pairUriPolicies(removed, added []UriPolicy) ([]UriPolicy, []UriPolicy, []UriPolicyChange) {
+	var (
+		paired   = make(map[int]bool)
+		rest     []UriPolicy
+		modified []UriPolicyChange
+		left     []UriPolicy
+	)
+
+	for _, old := range removed {
+		found := false
+		for i, v := range added {
+			if !paired[i] && v.Role == old.Role && v.Domain == old.Domain && v.Path == old.Path {
+				paired[i] = true
+				found = true
+				modified = append(modified, UriPolicyChange{Old: old, New: v})
+				break
+			}
+		}
+		if !found {
+			rest = append(rest, old)
+		}
+	}
+
+	for i, v := range added {
+		if !paired[i] {
+			left = append(left, v)
+		}
+	}
+
+	return rest, left, modified
+}
+
+// 角色与域相同的移除与新增政策视为修改，即修改父级角色
+func pairRolePolicies(removed, added []RolePolicy) ([]RolePolicy, []RolePolicy, []RolePolicyChange) {
+	var (
+		paired   = make(map[int]bool)
+		rest     []RolePolicy
+		modified []RolePolicyChange
+		left     []RolePolicy
+	)
+
+	for _, old := range removed {
+		found := false
+		for i, v := range added {
+			if !paired[i] && v.Role == old.Role && v.Domain == old.Domain {
+				paired[i] = true
+				found = true
+				modified = append(modified, RolePolicyChange{Old: old, New: v})
+				break
+			}
+		}
+		if !found {
+			rest = append(rest, old)
+		}
+	}
+	for i, v := range added {
+		if !paired[i] {
+			left = append(left, v)
+		}
+	}
+
+	return rest, left, modified
+}
+
+// 计算各角色有效权限的变化
+func diffImpacts(oldUps []UriPolicy, oldRps []RolePolicy, newUps []UriPolicy, newRps []RolePolicy) []PolicyImpact {
+	var (
+		olds    = effectivePolicies(oldUps, oldRps)
+		news    = effectivePolicies(newUps, newRps)
+		keys    []string
+		impacts []PolicyImpact
+	)
+
+	for k := range olds {
+		keys = append(keys, k)
+	}
+	for k := range news {
+		if _, ok := olds[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		var (
+			parts  = strings.SplitN(k, "\x00", 2)
+			impact = PolicyImpact{Domain: parts[0], Role: parts[1]}
+		)
+
+		impact.Gained = subtractPolicies(news[k], olds[k])
+		impact.Lost = subtractPolicies(olds[k], news[k])
+		if len(impact.Gained) > 0 || len(impact.Lost) > 0 {
+			impacts = append(impacts, impact)
+		}
+	}
+
+	return impacts
+}
+
+// 获取各角色的有效权限，键为 域\x00角色
+func effectivePolicies(ups []UriPolicy, rps []RolePolicy) map[string][]UriPolicy {
+	var (
+		owners   = make(map[string][]UriPolicy)
+		children = make(map[string][]string)
+		results  = make(map[string][]UriPolicy)
+	)
+
+	for _, v := range ups {
+		key := v.Domain + "\x00" + v.Role
+		owners[key] = append(owners[key], v)
+	}
+	for _, v := range rps {
+		// 没有资源访问政策的角色同样参与计算
+		if _, ok := children[v.Domain+"\x00"+v.Role]; !ok {
+			children[v.Domain+"\x00"+v.Role] = nil
+		}
+		if v.ParentRole != "" {
+			key := v.Domain + "\x00" + v.ParentRole
+			children[key] = append(children[key], v.Role)
+		}
+	}
+
+	roles := make(map[string]bool)
+	for k := range owners {
+		roles[k] = true
+	}
+	for k := range children {
+		roles[k] = true
+	}
+	for k := range roles {
+		var (
+			domain  = strings.SplitN(k, "\x00", 2)[0]
+			role    = strings.SplitN(k, "\x00", 2)[1]
+			visited = map[string]bool{role: true}
+			queue   = []string{role}
+		)
+
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			results[k] = append(results[k], owners[domain+"\x00"+current]...)
+			for _, v := range children[domain+"\x00"+current] {
+				if !visited[v] {
+					visited[v] = true
+					queue = append(queue, v)
+				}
+			}
+		}
+	}
+
+	return results
+}
+
+// 获取在a中但不在b中的政策，按路径、方法与效果比较，不区分政策所属的角色
+func subtractPolicies(a, b []UriPolicy) []UriPolicy {
+	var (
+		exists  = make(map[string]bool)
+		results []UriPolicy
+	)
+
+	key := func(p *UriPolicy) string {
+		rule := p.rule()
+		return strings.Join(rule[2:], "\x00")
+	}
+	for i := range b {
+		exists[key(&b[i])] = true
+	}
+	for i := range a {
+		k := key(&a[i])
+		if !exists[k] {
+			exists[k] = true
+			results = append(results, a[i])
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,116 @@
+package rbac
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffPolicies(t *testing.T) {
+	var (
+		oldUps = []UriPolicy{
+			{Role: "admin", Domain: "default", Path: "/users", Method: "GET"},
+			{Role: "editor", Domain: "default", Path: "/article", Method: "POST"},
+			{Role: "reader", Domain: "default", Path: "/article", Method: "GET"},
+		}
+		oldRps = []RolePolicy{
+			{Role: "admin", Domain: "default"},
+			{ParentRole: "admin", Role: "editor", Domain: "default"},
+			{ParentRole: "editor", Role: "reader", Domain: "default"},
+		}
+		newUps = []UriPolicy{
+			{Role: "admin", Domain: "default", Path: "/users", Method: "GET", Effect: EffectAllow},
+			{Role: "editor", Domain: "default", Path: "/article", Method: "POST|PUT"},
+			{Role: "reader", Domain: "default", Path: "/article", Method: "GET"},
+			{Role: "reader", Domain: "default", Path: "/comment", Method: "GET"},
+		}
+		newRps = []RolePolicy{
+			{Role: "admin", Domain: "default"},
+			{ParentRole: "admin", Role: "reader", Domain: "default"},
+			{Role: "editor", Domain: "default"},
+		}
+		d = DiffPolicies(oldUps, oldRps, newUps, newRps)
+	)
+
+	// 未设置效果视为 allow，不产生差异
+	assert.Equal(t, []UriPolicy{newUps[3]}, d.AddedUriPolicies)
+	assert.Empty(t, d.RemovedUriPolicies)
+	assert.Equal(t, []UriPolicyChange{{Old: oldUps[1], New: newUps[1]}}, d.ModifiedUriPolicies)
+	assert.Empty(t, d.AddedRolePolicies)
+	assert.Empty(t, d.RemovedRolePolicies)
+	assert.Equal(t, []RolePolicyChange{
+		{Old: oldRps[1], New: newRps[2]},
+		{Old: oldRps[2], New: newRps[1]},
+	}, d.ModifiedRolePolicies)
+	assert.False(t, d.Empty())
+
+	assert.Equal(t, []PolicyImpact{
+		{
+			Role:   "admin",
+			Domain: "default",
+			Gained: []UriPolicy{newUps[3]},
+			Lost:   []UriPolicy{oldUps[1]},
+		},
+		{
+			Role:   "editor",
+			Domain: "default",
+			Gained: []UriPolicy{newUps[1]},
+			Lost:   []UriPolicy{oldUps[1], oldUps[2]},
+		},
+		{
+			Role:   "reader",
+			Domain: "default",
+			Gained: []UriPolicy{newUps[3]},
+		},
+	}, d.Impacts)
+
+	assert.True(t, DiffPolicies(oldUps, oldRps, oldUps, oldRps).Empty())
+	// 没有父级角色与父级角色为 root 不同
+	d = DiffPolicies(nil, []RolePolicy{{Role: "admin", Domain: "default"}}, nil, []RolePolicy{{ParentRole: "root", Role: "admin", Domain: "default"}})
+	assert.Len(t, d.ModifiedRolePolicies, 1)
+	assert.Empty(t, DiffPolicies(oldUps, oldRps, oldUps, oldRps).Impacts)
+}
+
+func TestCasbin_DiffPolicies(t *testing.T) {
+	var (
+		path = filepath.Join(t.TempDir(), "policy.csv")
+		c    = NewCasbin(path)
+		ups  []UriPolicy
+		rps  []RolePolicy
+		d    *PolicyDiff
+		err  error
+	)
+
+	assert.NoError(t, os.WriteFile(path, []byte("p, role::reader, default, /article, GET\n"+
+		"p, role::writer, default, /article, POST\n"+
+		"g, root, role::reader, default\n"+
+		"g, role::writer, role::reader, default\n"), 0644))
+
+	ups, rps, err = ParsePolicyFile(path)
+	assert.NoError(t, err)
+	d, err = c.DiffPolicies(ups, rps)
+	assert.NoError(t, err)
+	assert.True(t, d.Empty())
+
+	// 拒绝政策使写入角色失去继承的读取权限
+	ups = append(ups, UriPolicy{Role: "writer", Domain: "default", Path: "/article", Method: "GET", Effect: EffectDeny})
+	d, err = c.DiffPolicies(ups, rps[:1])
+	assert.NoError(t, err)
+	assert.Equal(t, []UriPolicy{ups[2]}, d.AddedUriPolicies)
+	assert.Equal(t, []RolePolicy{rps[1]}, d.RemovedRolePolicies)
+	assert.Equal(t, []PolicyImpact{{
+		Role:   "writer",
+		Domain: "default",
+		Gained: []UriPolicy{ups[2]},
+		Lost:   []UriPolicy{{Role: "reader", Domain: "default", Path: "/article", Method: "GET", Effect: EffectAllow}},
+	}}, d.Impacts)
+
+	// 预览不改变当前的授权政策
+	assert.NoError(t, c.VerifyUriPolicy(&UriPolicy{Role: roleSubject("writer"), Domain: "default", Path: "/article", Method: "GET"}))
+
+	_, err = NewCasbin("").DiffPolicies(ups, rps)
+	assert.Error(t, err)
+	assert.Equal(t, ErrorPolicyFilePathInvalid, err.Error())
+}
@@ -0,0 +1,60 @@
+package rbac
+
+import (
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// 政策效果
+const (
+	EffectAllow = "allow" // 允许(默认)
+	EffectDeny  = "deny"  // 拒绝，优先于允许
+)
+
+// 不带效果字段的旧版政策模型，只用于加载旧版政策
+var legacyModelText = `
+ [request_definition]
+ r = sub, dom, obj, act
+
+ [policy_definition]
+ p = sub, dom, obj, act
+
+ [role_definition]
+ g = _, _, _
+
+ [policy_effect]
+ e = some(where (p.eft == allow))
+
+ [matchers]
+ m = r.sub == p.sub
+ `
+
+// 兼容旧版政策的适配器
+// Casbin加载政策时会丢弃字段数量与模型不一致的行，
+// 因此分别按旧版与当前模型加载，旧版政策补充 allow 效果后合并
+type effectAdapter struct {
+	persist.Adapter
+}
+
+// 加载全部政策
+func (a *effectAdapter) LoadPolicy(m model.Model) error {
+	var (
+		legacy model.Model
+		err    error
+	)
+
+	if err = a.Adapter.LoadPolicy(m); err != nil {
+		return err
+	}
+	if legacy, err = model.NewModelFromString(legacyModelText); err != nil {
+		return err
+	}
+	if err = a.Adapter.LoadPolicy(legacy); err != nil {
+		return err
+	}
+	for _, rule := range legacy["p"]["p"].Policy {
+		persist.LoadPolicyArray(append([]string{"p"}, append(rule, EffectAllow)...), m)
+	}
+
+	return nil
+}
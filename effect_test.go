@@ -0,0 +1,49 @@
+package rbac
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUriPolicy_FormatLine(t *testing.T) {
+	var (
+		p = UriPolicy{Role: "admin", Domain: "default", Path: "/admin/audit", Method: "DELETE"}
+	)
+
+	assert.Equal(t, "p, role::admin, default, /admin/audit, DELETE", p.FormatLine())
+	p.Effect = EffectDeny
+	assert.Equal(t, "p, role::admin, default, /admin/audit, DELETE, deny", p.FormatLine())
+}
+
+func TestRbac_DenyEffect(t *testing.T) {
+	var (
+		path   = filepath.Join(t.TempDir(), "policy.csv")
+		policy = "p, role::admin, default, /admin/*, *\n" +
+			"p, role::admin, default, /admin/audit, DELETE, deny\n" +
+			"p, role::editor, default, /article, GET, allow\n" +
+			"g, role::admin, role::editor, default\n"
+		r   *Rbac
+		err error
+	)
+
+	assert.NoError(t, os.WriteFile(path, []byte(policy), 0644))
+	r, err = New(Settings{
+		TokenSignKey:   []byte("gVoiG1fbXf65osbjfi33MZre"),
+		PolicyFilePath: path,
+		PathMatch:      PathMatchKeyMatch2,
+	})
+	assert.NoError(t, err)
+
+	// 旧版不带效果的政策默认允许
+	assert.NoError(t, r.VerifyRequest("/admin/users", "GET", "admin"))
+	assert.NoError(t, r.VerifyRequest("/admin/audit", "GET", "admin"))
+	// 拒绝优先于允许
+	assert.Error(t, r.VerifyRequest("/admin/audit", "DELETE", "admin"))
+	// 继承的政策
+	assert.NoError(t, r.VerifyRequest("/article", "GET", "admin"))
+	// 超级管理员不受拒绝政策影响
+	assert.NoError(t, r.VerifyRequest("/admin/audit", "DELETE", "root"))
+}
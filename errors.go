@@ -6,12 +6,58 @@ var (
 	ErrorRefreshTokenExpireTimeInvalid = "token refresh_token expiretime invalid"
 	ErrorTokenIssueTypeInvalid         = "token issue type invalid"
 	ErrorPolicyFilePathInvalid         = "policy file path invaild"
+	ErrorTokenRevoked                  = "token revoked"
+	ErrorTokenRefreshReused            = "token refresh_token reused, authorization family revoked"
+	ErrorRevocationFilePathInvalid     = "revocation file path invalid"
 
 	// Jwt
 	ErrorJwtSigningMethodInvaild = "token signing method invalid"
 	ErrorJwtParseInvaild         = "token parse invaid"
 	ErrorJwtClaimsInvaild        = "token claim invaid"
+	ErrorClaimsIssueTypeInvalid  = "token claim ist invalid"
+	ErrorClaimsSubjectMissing    = "token claim sub missing"
+	ErrorClaimsRoleMissing       = "token claim isr missing"
+	ErrorClaimsReserved          = "token claim name reserved"
+	ErrorJwtExpired              = "token expired"
+	ErrorJwtNotValidYet          = "token not valid yet"
+	ErrorJwtIssuedInFuture       = "token issued in the future"
+	ErrorJwtIssuedAtMissing      = "token claim iat missing"
+	ErrorJwtTooOld               = "token exceeds max age"
+	ErrorJwtIssuerInvalid        = "token issuer invalid"
+	ErrorJwtAudienceInvalid      = "token audience invalid"
+	ErrorJwtVerifyOnly           = "token signing key not set, verify only"
+	ErrorJwtKeyInvalid           = "token key invalid"
+	ErrorJwtKeyTypeInvalid       = "token key type not supported"
+	ErrorJwtKeyPEMInvalid        = "token key pem invalid"
+	ErrorJwtKeyNotFound          = "token key not found"
+	ErrorJwtKeyIdExists          = "token key id already exists"
+	ErrorJwtKeyActive            = "token key is active"
+
+	// Jwks
+	ErrorJwksInvalid       = "jwks invalid"
+	ErrorJwksFetchFailed   = "jwks fetch failed"
+	ErrorJwksFetcherNotSet = "jwks fetcher not set"
 
 	// Casbin
-	ErrorCasbinEnforceInvaild = "casbin enforce invaild"
+	ErrorCasbinEnforceInvaild   = "casbin enforce invaild"
+	ErrorCasbinPathMatchInvalid = "casbin path match invalid"
+	ErrorCasbinPolicyExists     = "casbin policy already exists"
+	ErrorCasbinPolicyNotFound   = "casbin policy not found"
+	ErrorWatcherFilePathInvalid = "watcher file path invalid"
+	ErrorWatcherNotifyFailed    = "policy changed, watcher notify failed"
+
+	// Policy
+	ErrorPolicyLineInvalid   = "policy line invalid"
+	ErrorPolicyTypeInvalid   = "policy type invalid, expected p or g"
+	ErrorPolicyFieldsInvalid = "policy fields invalid"
+	ErrorPolicyEffectInvalid = "policy effect invalid, expected allow or deny"
+	ErrorPolicyLintFailed    = "policy lint failed"
+	ErrorPolicyRoleCycle     = "policy role cycle"
+	ErrorPolicyRoleEmpty     = "policy parent role has no permissions"
+	ErrorPolicyDomainUnknown = "policy domain unknown"
+	ErrorPolicyDuplicate     = "policy duplicate"
+	ErrorPolicyShadowed      = "policy shadowed by another policy"
+	ErrorPolicyMethodInvalid = "policy method not supported"
+	ErrorPolicyPathInvalid   = "policy path invalid"
+	ErrorPolicyRolePrefixed  = "policy role double prefixed, remove the role:: prefix"
 )
@@ -0,0 +1,147 @@
+package rbac
+
+import (
+	"strings"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// 未命中的字段
+const (
+	MismatchRole   = "role"   // 角色及其继承的角色没有该政策
+	MismatchDomain = "domain" // 政策在其它域
+	MismatchPath   = "path"   // 路径不匹配
+	MismatchMethod = "method" // 请求方法不匹配
+)
+
+// 授权决定，说明验证请求放行或拒绝的原因
+type Decision struct {
+	Role       string        // 角色
+	Domain     string        // 域
+	Path       string        // 资源路径
+	Method     string        // 请求方法
+	Allowed    bool          // 是否放行
+	Super      bool          // 超级管理员直接放行
+	Matched    []PolicyMatch // 命中的政策，包含允许与拒绝政策，存在命中的拒绝政策时拒绝
+	NearMisses []NearMiss    // 拒绝时只差一个字段即可命中的允许政策
+}
+
+// 命中的政策
+type PolicyMatch struct {
+	Policy UriPolicy // 政策
+	Chain  []string  // 继承链，由请求的角色到政策所属的角色，直接命中时只有请求的角色
+}
+
+// 接近命中的政策
+type NearMiss struct {
+	Policy   UriPolicy // 政策
+	Mismatch string    // 未命中的字段，如 MismatchMethod
+	Chain    []string  // 继承链，角色未命中时为空
+}
+
+// 说明资源访问政策的验证结果，放行与否与VerifyUriPolicy一致，但不触发超级管理员的放行回调
+func (c *Casbin) ExplainUriPolicy(p *UriPolicy) (*Decision, error) {
+	var (
+		subject = roleSubject(p.Role)
+		d       = &Decision{
+			Role:   strings.TrimPrefix(p.Role, rolePrefix),
+			Domain: p.Domain,
+			Path:   p.Path,
+			Method: p.Method,
+		}
+		e      *casbin.Enforcer
+		chains = make(map[string]map[string][]string) // 各域的继承链
+		err    error
+	)
+
+	if c.SuperRole.match(subject, p.Domain) {
+		d.Allowed = true
+		d.Super = true
+		return d, nil
+	}
+	if e, err = c.enforcer(); err != nil {
+		return nil, err
+	}
+	if d.Allowed, err = e.Enforce(subject, p.Domain, p.Path, p.Method); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	pathMatch := c.PathMatch
+	c.mu.RUnlock()
+
+	for _, rule := range e.GetModel().GetPolicy("p", "p") {
+		var (
+			policy     = uriPolicyFromRule(rule)
+			reachable  = chains[policy.Domain]
+			pathOk     bool
+			mismatches []string
+		)
+
+		if pathOk, err = matchPath(pathMatch, p.Path, policy.Path); err != nil {
+			return nil, err
+		}
+		// 其它域中按该域的角色关系判断角色是否命中
+		if reachable == nil {
+			if reachable, err = c.roleChains(subject, policy.Domain); err != nil {
+				return nil, err
+			}
+			chains[policy.Domain] = reachable
+		}
+		chain, ok := reachable[rule[0]]
+		if policy.Domain != p.Domain {
+			mismatches = append(mismatches, MismatchDomain)
+		}
+		if !ok {
+			mismatches = append(mismatches, MismatchRole)
+		}
+		if !pathOk {
+			mismatches = append(mismatches, MismatchPath)
+		}
+		if !methodMatch(p.Method, policy.Method) {
+			mismatches = append(mismatches, MismatchMethod)
+		}
+
+		switch {
+		case len(mismatches) == 0:
+			d.Matched = append(d.Matched, PolicyMatch{Policy: policy, Chain: chain})
+		case len(mismatches) == 1 && !d.Allowed && policy.Effect != EffectDeny:
+			d.NearMisses = append(d.NearMisses, NearMiss{Policy: policy, Mismatch: mismatches[0], Chain: chain})
+		}
+	}
+
+	return d, nil
+}
+
+// 获取主体在指定域中可达的全部角色及继承链，包含主体自身
+func (c *Casbin) roleChains(subject, domain string) (map[string][]string, error) {
+	var (
+		rules  [][]string
+		queue  = []string{subject}
+		chains = map[string][]string{
+			subject: {strings.TrimPrefix(subject, rolePrefix)},
+		}
+		err error
+	)
+
+	if rules, err = c.domainRules("g", domain); err != nil {
+		return nil, err
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, rule := range rules {
+			// 超级管理员挂载顶级角色的关系不授予权限
+			if rule[0] != current || isTopLevelParent(rule[0], c.SuperRole.subject()) {
+				continue
+			}
+			if _, ok := chains[rule[1]]; ok {
+				continue
+			}
+			chain := append(append([]string{}, chains[current]...), strings.TrimPrefix(rule[1], rolePrefix))
+			chains[rule[1]] = chain
+			queue = append(queue, rule[1])
+		}
+	}
+
+	return chains, nil
+}
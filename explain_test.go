@@ -0,0 +1,140 @@
+package rbac
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRbac_ExplainRequest(t *testing.T) {
+	var (
+		path  = filepath.Join(t.TempDir(), "policy.csv")
+		lines = "p, role::admin, default, /users, GET\n" +
+			"p, role::editor, default, /article, GET|POST\n" +
+			"p, role::editor, default, /article, DELETE, deny\n" +
+			"p, role::reader, default, /article, GET\n" +
+			"p, role::reader, www, /comment, GET\n" +
+			"g, root, role::admin, default\n" +
+			"g, role::admin, role::editor, default\n" +
+			"g, role::editor, role::reader, default\n" +
+			"g, root, role::reader, www\n"
+		sets = Settings{
+			TokenSignKey:   []byte("gVoiG1fbXf65osbjfi33MZre"),
+			PolicyFilePath: path,
+		}
+		r   *Rbac
+		d   *Decision
+		err error
+	)
+
+	assert.NoError(t, os.WriteFile(path, []byte(lines), 0644))
+	r, err = New(sets)
+	assert.NoError(t, err)
+
+	t.Run("TestRbac_ExplainRequest_Inherited", func(t *testing.T) {
+		d, err = r.ExplainRequest("", "/article", "GET", "admin")
+		assert.NoError(t, err)
+		assert.True(t, d.Allowed)
+		assert.False(t, d.Super)
+		assert.Equal(t, "admin", d.Role)
+		assert.Equal(t, "default", d.Domain)
+		assert.Equal(t, []PolicyMatch{
+			{
+				Policy: UriPolicy{Role: "editor", Domain: "default", Path: "/article", Method: "GET|POST", Effect: EffectAllow},
+				Chain:  []string{"admin", "editor"},
+			},
+			{
+				Policy: UriPolicy{Role: "reader", Domain: "default", Path: "/article", Method: "GET", Effect: EffectAllow},
+				Chain:  []string{"admin", "editor", "reader"},
+			},
+		}, d.Matched)
+		assert.Empty(t, d.NearMisses)
+	})
+
+	t.Run("TestRbac_ExplainRequest_Deny", func(t *testing.T) {
+		d, err = r.ExplainRequest("", "/article", "DELETE", "editor")
+		assert.NoError(t, err)
+		assert.False(t, d.Allowed)
+		assert.Equal(t, []PolicyMatch{
+			{
+				Policy: UriPolicy{Role: "editor", Domain: "default", Path: "/article", Method: "DELETE", Effect: EffectDeny},
+				Chain:  []string{"editor"},
+			},
+		}, d.Matched)
+		assert.Equal(t, r.VerifyRequest("/article", "DELETE", "editor") == nil, d.Allowed)
+	})
+
+	t.Run("TestRbac_ExplainRequest_NearMisses", func(t *testing.T) {
+		// 方法不匹配
+		d, err = r.ExplainRequest("", "/article", "PUT", "reader")
+		assert.NoError(t, err)
+		assert.False(t, d.Allowed)
+		assert.Empty(t, d.Matched)
+		assert.Contains(t, d.NearMisses, NearMiss{
+			Policy:   UriPolicy{Role: "reader", Domain: "default", Path: "/article", Method: "GET", Effect: EffectAllow},
+			Mismatch: MismatchMethod,
+			Chain:    []string{"reader"},
+		})
+
+		// 角色缺少政策
+		d, err = r.ExplainRequest("", "/users", "GET", "reader")
+		assert.NoError(t, err)
+		assert.False(t, d.Allowed)
+		assert.Contains(t, d.NearMisses, NearMiss{
+			Policy:   UriPolicy{Role: "admin", Domain: "default", Path: "/users", Method: "GET", Effect: EffectAllow},
+			Mismatch: MismatchRole,
+		})
+
+		// 域错误
+		d, err = r.ExplainRequest("", "/comment", "GET", "reader")
+		assert.NoError(t, err)
+		assert.False(t, d.Allowed)
+		assert.Contains(t, d.NearMisses, NearMiss{
+			Policy:   UriPolicy{Role: "reader", Domain: "www", Path: "/comment", Method: "GET", Effect: EffectAllow},
+			Mismatch: MismatchDomain,
+			Chain:    []string{"reader"},
+		})
+
+		// 路径不匹配
+		d, err = r.ExplainRequest("www", "/comments", "GET", "reader")
+		assert.NoError(t, err)
+		assert.False(t, d.Allowed)
+		assert.Equal(t, []NearMiss{{
+			Policy:   UriPolicy{Role: "reader", Domain: "www", Path: "/comment", Method: "GET", Effect: EffectAllow},
+			Mismatch: MismatchPath,
+			Chain:    []string{"reader"},
+		}}, d.NearMisses)
+	})
+
+	t.Run("TestRbac_ExplainRequest_SuperRole", func(t *testing.T) {
+		var (
+			hooked bool
+		)
+
+		r.Casbin.SuperRole.Hook = func(*SuperRoleDecision) { hooked = true }
+		defer func() { r.Casbin.SuperRole.Hook = nil }()
+
+		d, err = r.ExplainRequest("", "/anything", "GET", "root")
+		assert.NoError(t, err)
+		assert.True(t, d.Allowed)
+		assert.True(t, d.Super)
+		assert.Empty(t, d.Matched)
+		// 说明不是真实的放行，不触发回调
+		assert.False(t, hooked)
+	})
+
+	t.Run("TestRbac_ExplainRequest_SuperRoleDisabled", func(t *testing.T) {
+		r.Casbin.SuperRole.Disabled = true
+		defer func() { r.Casbin.SuperRole.Disabled = false }()
+
+		// 挂载顶级角色的关系不授予权限，与验证请求的结果一致
+		d, err = r.ExplainRequest("", "/users", "GET", "root")
+		assert.NoError(t, err)
+		assert.False(t, d.Allowed)
+		assert.False(t, d.Super)
+		assert.Empty(t, d.Matched)
+		assert.Error(t, r.VerifyRequest("/users", "GET", "root"))
+	})
+}
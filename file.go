@@ -0,0 +1,56 @@
+package rbac
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// 原子写入文件：先写入同目录的临时文件并落盘，再重命名覆盖目标文件
+// 写入过程中崩溃不会留下残缺的目标文件
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	var (
+		dir  = filepath.Dir(path)
+		file *os.File
+		err  error
+	)
+
+	if file, err = os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*"); err != nil {
+		return err
+	}
+	// 失败时清理临时文件，重命名成功后Remove会返回错误，忽略即可
+	defer os.Remove(file.Name())
+
+	if _, err = file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Chmod(perm); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(file.Name(), path); err != nil {
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+// 目录落盘，保证重命名操作持久化
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	// 部分平台不支持目录Sync，忽略错误
+	d.Sync()
+	return nil
+}
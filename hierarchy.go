@@ -0,0 +1,157 @@
+package rbac
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// 角色层级查询
+// 角色关系政策 g, 父级角色, 角色, 域 表示父级角色继承角色的权限，
+// 因此角色的有效权限包含自身以及全部后代角色的资源访问政策
+
+// 获取角色在指定域中的全部祖先角色(传递)，按层级由近到远排列，不包含超级管理员
+func (c *Casbin) GetRoleAncestors(role, domain string) ([]string, error) {
+	rules, err := c.domainRules("g", domain)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.walkRoles(role, rules, func(rule []string) (string, string) {
+		return rule[1], rule[0]
+	}), nil
+}
+
+// 获取角色在指定域中的全部后代角色(传递)，按层级由近到远排列
+func (c *Casbin) GetRoleDescendants(role, domain string) ([]string, error) {
+	rules, err := c.domainRules("g", domain)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.walkRoles(role, rules, func(rule []string) (string, string) {
+		return rule[0], rule[1]
+	}), nil
+}
+
+// 获取角色在指定域中的有效权限，包含继承自后代角色的资源访问政策，Role为政策所属的角色
+func (c *Casbin) GetRolePermissions(role, domain string) ([]UriPolicy, error) {
+	var (
+		roles   []string
+		rules   [][]string
+		owners  = make(map[string]bool)
+		results []UriPolicy
+		err     error
+	)
+
+	if roles, err = c.GetRoleDescendants(role, domain); err != nil {
+		return nil, err
+	}
+	for _, v := range append([]string{strings.TrimPrefix(role, rolePrefix)}, roles...) {
+		owners[v] = true
+	}
+	if rules, err = c.domainRules("p", domain); err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		if p := uriPolicyFromRule(rule); owners[p.Role] {
+			results = append(results, p)
+		}
+	}
+
+	return results, nil
+}
+
+// 获取指定域中的全部角色并按名称排序，包含只出现在角色关系政策中的角色，不包含在该域生效的超级管理员
+func (c *Casbin) ListRoles(domain string) ([]string, error) {
+	var (
+		seen  = make(map[string]bool)
+		roles []string
+	)
+
+	add := func(subject string) {
+		if c.SuperRole.match(subject, domain) || seen[subject] {
+			return
+		}
+		seen[subject] = true
+		roles = append(roles, strings.TrimPrefix(subject, rolePrefix))
+	}
+
+	prules, err := c.domainRules("p", domain)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range prules {
+		add(rule[0])
+	}
+	grules, err := c.domainRules("g", domain)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range grules {
+		// 超级管理员挂载顶级角色的关系不代表拥有该角色
+		if !isTopLevelParent(rule[0], c.SuperRole.subject()) {
+			add(rule[0])
+		}
+		add(rule[1])
+	}
+	sort.Strings(roles)
+
+	return roles, nil
+}
+
+// 获取当前执行器中指定域的政策规则
+func (c *Casbin) domainRules(sec, domain string) ([][]string, error) {
+	var (
+		e     *casbin.Enforcer
+		rules [][]string
+		err   error
+	)
+
+	if e, err = c.enforcer(); err != nil {
+		return nil, err
+	}
+	// 资源访问政策的域为第2个字段，角色关系政策的域为第3个字段
+	index := 1
+	if sec == "g" {
+		index = 2
+	}
+	for _, rule := range e.GetModel().GetPolicy(sec, sec) {
+		if len(rule) > index && rule[index] == domain {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules, nil
+}
+
+// 按角色关系广度优先遍历，edge返回关系的起点与终点，角色可以带 role:: 前缀，忽略超级管理员与循环
+func (c *Casbin) walkRoles(role string, rules [][]string, edge func(rule []string) (string, string)) []string {
+	var (
+		start   = roleSubject(role)
+		visited = map[string]bool{start: true}
+		queue   = []string{start}
+		results []string
+	)
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, rule := range rules {
+			// 超级管理员与顶级角色之间的关系只记录层级，不授予权限
+			if isTopLevelParent(rule[0], c.SuperRole.subject()) {
+				continue
+			}
+			from, to := edge(rule)
+			if from != current || visited[to] {
+				continue
+			}
+			visited[to] = true
+			queue = append(queue, to)
+			results = append(results, strings.TrimPrefix(to, rolePrefix))
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,109 @@
+package rbac
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCasbin_RoleHierarchy(t *testing.T) {
+	var (
+		path  = filepath.Join(t.TempDir(), "policy.csv")
+		lines = "p, role::admin, manager, /users, GET\n" +
+			"p, role::editor, manager, /article, POST\n" +
+			"p, role::editor, manager, /article, DELETE, deny\n" +
+			"p, role::reader, manager, /article, GET\n" +
+			"p, role::reader, www, /article, GET\n" +
+			"g, root, role::admin, manager\n" +
+			"g, role::admin, role::editor, manager\n" +
+			"g, role::editor, role::reader, manager\n" +
+			"g, root, role::reader, www\n" +
+			"g, role::a, role::b, loop\n" +
+			"g, role::b, role::a, loop\n"
+		c   *Casbin
+		err error
+	)
+
+	assert.NoError(t, os.WriteFile(path, []byte(lines), 0644))
+	c = NewCasbin(path)
+	// 循环的角色关系无法通过政策检查，这里验证查询对循环的容错
+	c.PolicyLint.Disabled = true
+	assert.NoError(t, c.Init())
+
+	t.Run("TestCasbin_GetRoleAncestors", func(t *testing.T) {
+		roles, err := c.GetRoleAncestors("reader", "manager")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"editor", "admin"}, roles)
+
+		roles, err = c.GetRoleAncestors("reader", "www")
+		assert.NoError(t, err)
+		assert.Empty(t, roles)
+	})
+
+	t.Run("TestCasbin_RoleHierarchy_Cycle", func(t *testing.T) {
+		roles, err := c.GetRoleAncestors("a", "loop")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"b"}, roles)
+
+		roles, err = c.GetRoleDescendants("a", "loop")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"b"}, roles)
+	})
+
+	t.Run("TestCasbin_GetRoleDescendants", func(t *testing.T) {
+		roles, err := c.GetRoleDescendants("admin", "manager")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"editor", "reader"}, roles)
+
+		roles, err = c.GetRoleDescendants("admin", "www")
+		assert.NoError(t, err)
+		assert.Empty(t, roles)
+	})
+
+	t.Run("TestCasbin_GetRolePermissions", func(t *testing.T) {
+		ps, err := c.GetRolePermissions("editor", "manager")
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []UriPolicy{
+			{Role: "editor", Domain: "manager", Path: "/article", Method: "POST", Effect: EffectAllow},
+			{Role: "editor", Domain: "manager", Path: "/article", Method: "DELETE", Effect: EffectDeny},
+			{Role: "reader", Domain: "manager", Path: "/article", Method: "GET", Effect: EffectAllow},
+		}, ps)
+
+		ps, err = c.GetRolePermissions("reader", "www")
+		assert.NoError(t, err)
+		assert.Equal(t, []UriPolicy{
+			{Role: "reader", Domain: "www", Path: "/article", Method: "GET", Effect: EffectAllow},
+		}, ps)
+	})
+
+	t.Run("TestCasbin_RoleHierarchy_Prefixed", func(t *testing.T) {
+		// 带 role:: 前缀的角色与不带前缀的结果相同
+		roles, err := c.GetRoleAncestors("role::reader", "manager")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"editor", "admin"}, roles)
+		roles, err = c.GetRoleDescendants("role::admin", "manager")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"editor", "reader"}, roles)
+		ps, err := c.GetRolePermissions("role::reader", "www")
+		assert.NoError(t, err)
+		assert.Len(t, ps, 1)
+	})
+
+	t.Run("TestCasbin_ListRoles", func(t *testing.T) {
+		roles, err := c.ListRoles("manager")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"admin", "editor", "reader"}, roles)
+
+		roles, err = c.ListRoles("unknown")
+		assert.NoError(t, err)
+		assert.Empty(t, roles)
+	})
+
+	t.Run("TestCasbin_RoleHierarchy_PolicyFileInvalid", func(t *testing.T) {
+		_, err = NewCasbin("").GetRoleAncestors("admin", "manager")
+		assert.Error(t, err)
+		assert.Equal(t, ErrorPolicyFilePathInvalid, err.Error())
+	})
+}
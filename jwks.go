@@ -0,0 +1,372 @@
+package rbac
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"time"
+
+	pkg "github.com/golang-jwt/jwt/v4"
+)
+
+// JWKS发布路径
+const JWKSPath = "/.well-known/jwks.json"
+
+// 未知kid触发重新获取JWKS的默认最小间隔，防止伪造kid的Token导致频繁请求
+const jwksRefreshInterval = time.Minute
+
+// JSON Web Key (RFC 7517)，只包含公钥部分
+type JWK struct {
+	Kty string `json:"kty"`           // 密钥类型，RSA/EC/OKP
+	Kid string `json:"kid,omitempty"` // 密钥ID
+	Use string `json:"use,omitempty"` // 用途，sig=签名
+	Alg string `json:"alg,omitempty"` // 签名算法
+	N   string `json:"n,omitempty"`   // RSA模数
+	E   string `json:"e,omitempty"`   // RSA指数
+	Crv string `json:"crv,omitempty"` // 曲线，P-256/P-384/P-521/Ed25519
+	X   string `json:"x,omitempty"`   // EC/OKP公钥X坐标
+	Y   string `json:"y,omitempty"`   // EC公钥Y坐标
+}
+
+// JSON Web Key Set
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS获取器
+type JWKSFetcher interface {
+	FetchJWKS() ([]byte, error)
+}
+
+// 函数形式的JWKS获取器
+type JWKSFetcherFunc func() ([]byte, error)
+
+// 获取JWKS文档
+func (f JWKSFetcherFunc) FetchJWKS() ([]byte, error) {
+	return f()
+}
+
+// 从文件获取JWKS文档
+func NewFileJWKSFetcher(path string) JWKSFetcher {
+	return JWKSFetcherFunc(func() ([]byte, error) {
+		return os.ReadFile(path)
+	})
+}
+
+// 从HTTP地址获取JWKS文档，client为空时使用http.DefaultClient
+func NewHTTPJWKSFetcher(url string, client *http.Client) JWKSFetcher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return JWKSFetcherFunc(func() ([]byte, error) {
+		resp, err := client.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s: %s", ErrorJwksFetchFailed, resp.Status)
+		}
+		return io.ReadAll(resp.Body)
+	})
+}
+
+// 导出签名密钥的公钥部分，HMAC密钥不能导出
+func NewJWK(key *SigningKey) (*JWK, error) {
+	var (
+		jwk = &JWK{
+			Kid: key.Kid,
+			Use: "sig",
+			Alg: key.Method.Alg(),
+		}
+	)
+
+	switch pub := key.VerifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeJWKBytes(pub.N.Bytes())
+		jwk.E = encodeJWKBytes(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = encodeJWKBytes(pub.X.FillBytes(make([]byte, size)))
+		jwk.Y = encodeJWKBytes(pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encodeJWKBytes(pub)
+	default:
+		return nil, errors.New(ErrorJwtKeyTypeInvalid)
+	}
+
+	return jwk, nil
+}
+
+// 转为只验证的签名密钥
+func (j *JWK) SigningKey() (*SigningKey, error) {
+	var (
+		pub crypto.PublicKey
+		key *SigningKey
+		err error
+	)
+
+	if pub, err = j.publicKey(); err != nil {
+		return nil, err
+	}
+	if key, err = NewVerifyKey(pub); err != nil {
+		return nil, err
+	}
+	key.Kid = j.Kid
+	// 声明了算法时以声明为准，但必须与密钥类型匹配
+	if j.Alg != "" && j.Alg != key.Method.Alg() {
+		method := pkg.GetSigningMethod(j.Alg)
+		if method == nil || !methodAcceptsKey(method, pub) {
+			return nil, errors.New(ErrorJwksInvalid)
+		}
+		key.Method = method
+	}
+
+	return key, nil
+}
+
+// RFC 7638 JWK指纹，可用作kid
+func (j *JWK) Thumbprint() (string, error) {
+	var (
+		members map[string]string
+		data    []byte
+		err     error
+	)
+
+	// 只包含必需成员，json.Marshal会按字典序输出
+	switch j.Kty {
+	case "RSA":
+		members = map[string]string{"e": j.E, "kty": j.Kty, "n": j.N}
+	case "EC":
+		members = map[string]string{"crv": j.Crv, "kty": j.Kty, "x": j.X, "y": j.Y}
+	case "OKP":
+		members = map[string]string{"crv": j.Crv, "kty": j.Kty, "x": j.X}
+	default:
+		return "", errors.New(ErrorJwtKeyTypeInvalid)
+	}
+	if data, err = json.Marshal(members); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+
+	return encodeJWKBytes(sum[:]), nil
+}
+
+// 解析JWKS文档，跳过非签名用途以及不支持的密钥类型
+func ParseJWKS(data []byte) ([]*SigningKey, error) {
+	var (
+		set  JWKSet
+		keys []*SigningKey
+	)
+
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, errors.New(ErrorJwksInvalid)
+	}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if jwk.Kty != "RSA" && jwk.Kty != "EC" && jwk.Kty != "OKP" {
+			continue
+		}
+		key, err := jwk.SigningKey()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// 导出密钥环中全部非对称密钥的公钥部分
+func (k *KeyRing) JWKS() *JWKSet {
+	var (
+		set = &JWKSet{Keys: []JWK{}}
+	)
+
+	for _, key := range k.Keys() {
+		jwk, err := NewJWK(key)
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, *jwk)
+	}
+
+	return set
+}
+
+// 设置JWKS获取器并立即加载，此后密钥环只包含JWKS中的验证密钥
+func (j *Jwt) SetJWKSFetcher(fetcher JWKSFetcher) error {
+	j.fetchMu.Lock()
+	j.fetcher = fetcher
+	j.fetchMu.Unlock()
+
+	return j.RefreshJWKS()
+}
+
+// 设置未知kid触发重新获取JWKS的最小间隔，默认1分钟，0为不限制
+func (j *Jwt) SetJWKSRefreshInterval(interval time.Duration) {
+	j.fetchMu.Lock()
+	j.refresh = interval
+	j.fetchMu.Unlock()
+}
+
+// 重新获取JWKS并替换密钥环中的密钥
+func (j *Jwt) RefreshJWKS() error {
+	var (
+		data []byte
+		keys []*SigningKey
+		err  error
+	)
+
+	j.fetchMu.Lock()
+	defer j.fetchMu.Unlock()
+
+	if j.fetcher == nil {
+		return errors.New(ErrorJwksFetcherNotSet)
+	}
+	j.fetchedAt = time.Now()
+	if data, err = j.fetcher.FetchJWKS(); err != nil {
+		return err
+	}
+	if keys, err = ParseJWKS(data); err != nil {
+		return err
+	}
+
+	return j.keys.Replace(keys)
+}
+
+// 遇到未知kid时重新获取JWKS，签发方轮换密钥后验证方无需重启
+func (j *Jwt) refreshJWKSForKid(kid string) (*SigningKey, bool) {
+	j.fetchMu.Lock()
+	ready := j.fetcher != nil && time.Since(j.fetchedAt) >= j.refresh
+	j.fetchMu.Unlock()
+
+	if !ready || j.RefreshJWKS() != nil {
+		return nil, false
+	}
+	return j.keys.Key(kid)
+}
+
+// JWKS发布处理器，在 /.well-known/jwks.json 提供密钥环的公钥
+func (j *Jwt) JWKSHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != JWKSPath {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		data, err := json.Marshal(j.keys.JWKS())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		w.Write(data)
+	})
+}
+
+// 获取JWK中的公钥
+func (j *JWK) publicKey() (crypto.PublicKey, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeJWKInt(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeJWKInt(j.E)
+		if err != nil || !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New(ErrorJwksInvalid)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.New(ErrorJwtKeyTypeInvalid)
+		}
+		x, err := decodeJWKInt(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeJWKInt(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New(ErrorJwksInvalid)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, errors.New(ErrorJwtKeyTypeInvalid)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New(ErrorJwksInvalid)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, errors.New(ErrorJwtKeyTypeInvalid)
+}
+
+// 签名算法是否适用于公钥
+func methodAcceptsKey(method pkg.SigningMethod, pub crypto.PublicKey) bool {
+	switch m := method.(type) {
+	case *pkg.SigningMethodRSA, *pkg.SigningMethodRSAPSS:
+		_, ok := pub.(*rsa.PublicKey)
+		return ok
+	case *pkg.SigningMethodECDSA:
+		k, ok := pub.(*ecdsa.PublicKey)
+		return ok && k.Curve.Params().BitSize == m.CurveBits
+	case *pkg.SigningMethodEd25519:
+		_, ok := pub.(ed25519.PublicKey)
+		return ok
+	}
+
+	return false
+}
+
+// base64url编码(无填充)
+func encodeJWKBytes(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// base64url解码为大整数
+func decodeJWKInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New(ErrorJwksInvalid)
+	}
+	return new(big.Int).SetBytes(b), nil
+}
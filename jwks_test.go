@@ -0,0 +1,120 @@
+package rbac
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJWK_Thumbprint(t *testing.T) {
+	// RFC 7638 3.1 示例
+	jwk := &JWK{
+		Kty: "RSA",
+		N:   "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		E:   "AQAB",
+	}
+
+	thumbprint, err := jwk.Thumbprint()
+	assert.NoError(t, err)
+	assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", thumbprint)
+}
+
+func TestJwt_JWKSHandler(t *testing.T) {
+	var (
+		rsaKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+		key, _    = NewSignerKey(rsaKey)
+		j         = NewJwtWithKeyRing(NewKeyRing(key), "lgcgo.com")
+		set       JWKSet
+	)
+
+	key.Kid = "2022-07"
+
+	t.Run("TestJwt_JWKSHandler_Get", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		j.JWKSHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, JWKSPath, nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &set))
+		assert.Len(t, set.Keys, 1)
+		assert.Equal(t, "RSA", set.Keys[0].Kty)
+		assert.Equal(t, "2022-07", set.Keys[0].Kid)
+		assert.Equal(t, "RS256", set.Keys[0].Alg)
+	})
+
+	t.Run("TestJwt_JWKSHandler_NotFound", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		j.JWKSHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jwks.json", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("TestJwt_JWKSHandler_MethodNotAllowed", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		j.JWKSHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, JWKSPath, nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	})
+}
+
+func TestNew_JWKSVerifier(t *testing.T) {
+	var (
+		ecKey, _      = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		newKey, _     = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		signKey, _    = NewSignerKey(ecKey)
+		rotateKey, _  = NewSignerKey(newKey)
+		issuer        = NewJwtWithKeyRing(NewKeyRing(signKey), "lgcgo.com")
+		iClaims       = &IssueClaims{Type: "grant", Role: "admin", Subject: "uid001"}
+		expireTime, _ = time.ParseDuration("1h")
+		documents     [][]byte
+		fetches       int
+	)
+
+	signKey.Kid = "2022-07"
+	rotateKey.Kid = "2022-08"
+
+	// 签发方轮换前后各发布一次JWKS
+	oldTicket, err := issuer.IssueToken(iClaims, expireTime)
+	assert.NoError(t, err)
+	document, _ := json.Marshal(issuer.KeyRing().JWKS())
+	documents = append(documents, document)
+
+	assert.NoError(t, issuer.KeyRing().Rotate(rotateKey, expireTime))
+	newTicket, err := issuer.IssueToken(iClaims, expireTime)
+	assert.NoError(t, err)
+	document, _ = json.Marshal(issuer.KeyRing().JWKS())
+	documents = append(documents, document)
+
+	fetcher := JWKSFetcherFunc(func() ([]byte, error) {
+		document := documents[fetches]
+		fetches++
+		return document, nil
+	})
+	verifier, err := New(Settings{JWKSFetcher: fetcher, TokenIssuer: "lgcgo.com", JWKSRefreshInterval: time.Hour})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fetches)
+	_, err = verifier.Jwt.ParseToken(oldTicket)
+	assert.NoError(t, err)
+
+	// 只验证实例不能签发
+	_, err = verifier.Authorization("uid001", "admin")
+	assert.Error(t, err)
+	assert.Equal(t, ErrorJwtVerifyOnly, err.Error())
+
+	// 最小间隔内遇到未知kid不重新获取JWKS
+	_, err = verifier.Jwt.ParseToken(newTicket)
+	assert.Error(t, err)
+	assert.Equal(t, 1, fetches)
+
+	// 遇到未知kid时重新获取JWKS
+	verifier.Jwt.SetJWKSRefreshInterval(0)
+	_, err = verifier.Jwt.ParseToken(newTicket)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fetches)
+}
@@ -9,15 +9,40 @@
 package rbac
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"strings"
+	"sync"
 	"time"
 
 	pkg "github.com/golang-jwt/jwt/v4"
 )
 
 type Jwt struct {
-	signKey []byte // 加密密钥
-	issuer  string // 签发者
+	keys      *KeyRing      // 签名密钥环
+	issuer    string        // 签发者
+	options   VerifyOptions // 验证选项
+	fetcher   JWKSFetcher   // JWKS获取器，设置后为JWKS验证模式
+	fetchMu   sync.Mutex
+	fetchedAt time.Time     // 最近一次获取JWKS的时间
+	refresh   time.Duration // 未知kid触发重新获取JWKS的最小间隔
+}
+
+// 授众匹配方式
+const (
+	AudienceMatchAny = "any" // 包含任一要求的授众
+	AudienceMatchAll = "all" // 包含全部要求的授众
+)
+
+// Token验证选项
+type VerifyOptions struct {
+	Issuer        string        // 要求的签发者，为空时不验证
+	Audience      []string      // 要求的授众，为空时不验证
+	AudienceMatch string        // 授众匹配方式，默认any
+	Leeway        time.Duration // 允许的时钟偏差，用于exp、nbf、iat的验证
+	MaxAge        time.Duration // 签发后的最长使用时间，0为不限制
 }
 
 // 声明格式
@@ -30,49 +55,267 @@ type Jwt struct {
 // - nbf (Not Before)：生效时间
 // - jti (JWT ID)：编号
 type Claims struct {
-	IssueType string `json:"ist"` // 签发类型, grant=授予,renew=刷新
-	IssueRole string `json:"isr"` // 签发角色, 签发的角色名称（允许多角色）
+	IssueType   string              `json:"ist"`           // 签发类型, grant=授予,renew=刷新
+	IssueRole   string              `json:"isr"`           // 签发角色, 签发的主角色名称
+	IssueRoles  []string            `json:"irs,omitempty"` // 签发角色列表, 多角色时在所有域中有效
+	DomainRoles map[string][]string `json:"idr,omitempty"` // 域角色, 只在指定域中有效的角色
+	Domain      string              `json:"dom,omitempty"` // 签发域, 角色被授予的域(租户)
+	Family      string              `json:"fam,omitempty"` // 授权家族, 同一次授权及其后续刷新签发的Token属于同一家族
+	IssuedAtNs  int64               `json:"ins,omitempty"` // 签发时间(纳秒), iat只精确到秒, 用于判断是否在主题吊销之前签发
 	pkg.RegisteredClaims
+	Extra map[string]interface{} `json:"-"` // 自定义声明, 如租户ID、显示名称、权限范围等
+}
+
+// 保留的声明名称，自定义声明不能覆盖
+var reservedClaims = map[string]bool{
+	"ist": true, "isr": true, "irs": true, "idr": true, "dom": true, "fam": true, "ins": true,
+	"iss": true, "sub": true, "aud": true, "exp": true, "nbf": true, "iat": true, "jti": true,
+}
+
+// 验证声明，在官方字段的时间验证之外，要求签发类型、主题与角色合法
+func (c *Claims) Valid() error {
+	if err := c.RegisteredClaims.Valid(); err != nil {
+		return err
+	}
+	return c.validFields()
+}
+
+// 验证签发类型、主题与角色
+func (c *Claims) validFields() error {
+	if c.IssueType != "grant" && c.IssueType != "renew" {
+		return errors.New(ErrorClaimsIssueTypeInvalid)
+	}
+	if c.Subject == "" {
+		return errors.New(ErrorClaimsSubjectMissing)
+	}
+	if c.IssueRole == "" && len(c.IssueRoles) == 0 && len(c.DomainRoles) == 0 {
+		return errors.New(ErrorClaimsRoleMissing)
+	}
+
+	return nil
+}
+
+// 获取签发类型
+func (c *Claims) GetIssueType() string {
+	return c.IssueType
+}
+
+// 获取签发角色
+func (c *Claims) GetRole() string {
+	return c.IssueRole
+}
+
+// 获取在所有域中有效的角色，包含主角色，已去重
+func (c *Claims) GetRoles() []string {
+	return uniqueRoles([]string{c.IssueRole}, c.IssueRoles)
+}
+
+// 获取在指定域中有效的角色，包含所有域中有效的角色以及该域的域角色
+func (c *Claims) GetDomainRoles(domain string) []string {
+	return uniqueRoles(c.GetRoles(), c.DomainRoles[domain])
+}
+
+// 获取签发域，未指定时为空
+func (c *Claims) GetDomain() string {
+	return c.Domain
+}
+
+// 获取主题，一般为用户的唯一标识
+func (c *Claims) GetSubject() string {
+	return c.Subject
+}
+
+// 获取签发者
+func (c *Claims) GetIssuer() string {
+	return c.Issuer
+}
+
+// 获取授众
+func (c *Claims) GetAudience() []string {
+	return c.Audience
+}
+
+// 获取Token编号
+func (c *Claims) GetID() string {
+	return c.ID
+}
+
+// 获取授权家族
+func (c *Claims) GetFamily() string {
+	return c.Family
+}
+
+// 获取自定义声明
+func (c *Claims) GetExtra(name string) (interface{}, bool) {
+	v, ok := c.Extra[name]
+	return v, ok
+}
+
+// 将全部声明（含自定义声明）解码到v，v可以是嵌入了Claims的自定义结构体
+func (c *Claims) Decode(v interface{}) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	if data, err = c.MarshalAll(); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// 编码全部声明（含自定义声明）
+func (c *Claims) MarshalAll() ([]byte, error) {
+	var (
+		fields map[string]interface{}
+		data   []byte
+		err    error
+	)
+
+	if data, err = json.Marshal(c); err != nil {
+		return nil, err
+	}
+	if len(c.Extra) == 0 {
+		return data, nil
+	}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for name, v := range c.Extra {
+		if !reservedClaims[name] {
+			fields[name] = v
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// 获取过期时间，未设置时返回零值
+func (c *Claims) GetExpiresAt() time.Time {
+	return numericDateTime(c.ExpiresAt)
+}
+
+// 获取签发时间，未设置时返回零值
+func (c *Claims) GetIssuedAt() time.Time {
+	return numericDateTime(c.IssuedAt)
+}
+
+// 获取精确的签发时间，没有ins声明时使用iat
+func (c *Claims) issuedAtPrecise() time.Time {
+	if c.IssuedAtNs > 0 {
+		return time.Unix(0, c.IssuedAtNs)
+	}
+	return c.GetIssuedAt()
+}
+
+// 获取生效时间，未设置时返回零值
+func (c *Claims) GetNotBefore() time.Time {
+	return numericDateTime(c.NotBefore)
 }
 
 // 签发字段
 type IssueClaims struct {
-	Type     string   // 签发类型，这里 grant=授权, renew=刷新
-	Role     string   // 签发角色，相同角色具备相同的权限
-	Subject  string   // 签发主题，一般用使用用户的唯一标识
-	Audience []string // 签发授众，例如指定的浏览器、应用标识等
+	Type        string                 // 签发类型，这里 grant=授权, renew=刷新
+	Role        string                 // 签发角色，相同角色具备相同的权限
+	Roles       []string               // 签发角色列表，多角色时使用
+	DomainRoles map[string][]string    // 域角色，只在指定域中有效
+	Domain      string                 // 签发域，角色被授予的域(租户)
+	Subject     string                 // 签发主题，一般用使用用户的唯一标识
+	Audience    []string               // 签发授众，例如指定的浏览器、应用标识等
+	Family      string                 // 授权家族，刷新授权时沿用
+	Extra       map[string]interface{} // 自定义声明，不能使用保留的声明名称
 }
 
-var insJwt = &Jwt{}
+// 签发用声明，序列化时合并自定义声明
+type signedClaims struct {
+	*Claims
+}
+
+// 序列化
+func (c signedClaims) MarshalJSON() ([]byte, error) {
+	return c.Claims.MarshalAll()
+}
 
+// 实例化Jwt，使用HMAC(HS256)签名
 func NewJwt(signKey []byte, issuer string) *Jwt {
-	return insJwt
+	return NewJwtWithKey(NewHMACKey(signKey), issuer)
+}
+
+// 使用指定的签名密钥实例化Jwt，支持HMAC、RSA、ECDSA以及Ed25519
+func NewJwtWithKey(key *SigningKey, issuer string) *Jwt {
+	return NewJwtWithKeyRing(NewKeyRing(key), issuer)
+}
+
+// 使用密钥环实例化Jwt，支持密钥轮换；每次调用返回独立的实例
+func NewJwtWithKeyRing(keys *KeyRing, issuer string) *Jwt {
+	return &Jwt{
+		keys:    keys,
+		issuer:  issuer,
+		options: VerifyOptions{Issuer: issuer},
+		refresh: jwksRefreshInterval,
+	}
+}
+
+// 设置验证选项
+func (j *Jwt) SetVerifyOptions(opts VerifyOptions) {
+	j.options = opts
+}
+
+// 获取签名密钥环
+func (j *Jwt) KeyRing() *KeyRing {
+	return j.keys
 }
 
 // 签发Token
 func (j *Jwt) IssueToken(iClaims *IssueClaims, expireTime time.Duration) (string, error) {
 	var (
+		key    *SigningKey
 		token  *pkg.Token
 		ticket string
+		jti    string
 		err    error
 	)
 
+	// 只验证实例不允许签发
+	if key = j.keys.Active(); key == nil {
+		return "", errors.New(ErrorJwtVerifyOnly)
+	}
+	// 自定义声明不能覆盖保留声明
+	for name := range iClaims.Extra {
+		if reservedClaims[name] {
+			return "", errors.New(ErrorClaimsReserved)
+		}
+	}
+	// 生成Token唯一编号，用于吊销
+	if jti, err = newTokenId(); err != nil {
+		return "", err
+	}
 	// 创建签名
+	now := time.Now()
 	claims := &Claims{
-		iClaims.Type,
-		iClaims.Role,
-		pkg.RegisteredClaims{
+		IssueType:   iClaims.Type,
+		IssueRole:   iClaims.Role,
+		IssueRoles:  iClaims.Roles,
+		DomainRoles: iClaims.DomainRoles,
+		Domain:      iClaims.Domain,
+		Family:      iClaims.Family,
+		IssuedAtNs:  now.UnixNano(),
+		RegisteredClaims: pkg.RegisteredClaims{
 			Issuer:    j.issuer,
 			Subject:   iClaims.Subject,
 			Audience:  iClaims.Audience,
-			ExpiresAt: pkg.NewNumericDate(time.Now().Add(expireTime)),
-			NotBefore: pkg.NewNumericDate(time.Now()),
-			IssuedAt:  pkg.NewNumericDate(time.Now()),
+			ExpiresAt: pkg.NewNumericDate(now.Add(expireTime)),
+			NotBefore: pkg.NewNumericDate(now),
+			IssuedAt:  pkg.NewNumericDate(now),
+			ID:        jti,
 		},
+		Extra: iClaims.Extra,
 	}
 	// 生成token
-	token = pkg.NewWithClaims(pkg.SigningMethodHS256, claims)
-	if ticket, err = token.SignedString(j.signKey); err != nil {
+	token = pkg.NewWithClaims(key.Method, signedClaims{claims})
+	if key.Kid != "" {
+		token.Header["kid"] = key.Kid
+	}
+	if ticket, err = token.SignedString(key.SignKey); err != nil {
 		return "", err
 	}
 
@@ -80,27 +323,175 @@ func (j *Jwt) IssueToken(iClaims *IssueClaims, expireTime time.Duration) (string
 }
 
 // 解析Token
-func (j *Jwt) ParseToken(ticket string) (map[string]interface{}, error) {
+func (j *Jwt) ParseToken(ticket string) (*Claims, error) {
 	var (
 		token  *pkg.Token
-		claims map[string]interface{}
+		claims = &Claims{}
 		err    error
-		ok     bool
+		vErr   *pkg.ValidationError
+		tErr   *json.UnmarshalTypeError
 	)
 
-	// 解析Token对象
-	if token, err = pkg.Parse(ticket, func(token *pkg.Token) (interface{}, error) {
-		if _, ok = token.Method.(*pkg.SigningMethodHMAC); !ok {
+	// 解析Token对象并验证签名，声明在签名验证通过后按验证选项校验
+	if token, err = pkg.NewParser(pkg.WithoutClaimsValidation()).ParseWithClaims(ticket, claims, func(token *pkg.Token) (interface{}, error) {
+		// 根据kid获取密钥，未设置kid的Token使用kid为空的密钥
+		kid, _ := token.Header["kid"].(string)
+		key, ok := j.keys.Key(kid)
+		if !ok {
+			// JWKS验证模式下，签发方可能已轮换密钥
+			if key, ok = j.refreshJWKSForKid(kid); !ok {
+				return nil, errors.New(ErrorJwtKeyNotFound)
+			}
+		}
+		// 签名算法必须与密钥一致，防止算法混淆攻击
+		if token.Method.Alg() != key.Method.Alg() {
 			return nil, errors.New(ErrorJwtSigningMethodInvaild)
 		}
-		return j.signKey, nil
+		return key.VerifyKey, nil
 	}); err != nil {
+		// 声明类型错误(如isr不是字符串)
+		if errors.As(err, &vErr) && vErr.Errors == pkg.ValidationErrorMalformed && errors.As(vErr.Inner, &tErr) {
+			return nil, errors.New(ErrorJwtClaimsInvaild)
+		}
 		return nil, errors.New(ErrorJwtParseInvaild)
 	}
 	// 验证签名
-	if claims, ok = token.Claims.(pkg.MapClaims); !ok || !token.Valid {
+	if !token.Valid {
+		return nil, errors.New(ErrorJwtClaimsInvaild)
+	}
+	// 验证声明
+	if err = j.verifyClaims(claims, time.Now()); err != nil {
+		return nil, err
+	}
+	// 读取自定义声明
+	if claims.Extra, err = extraClaims(token.Raw); err != nil {
 		return nil, errors.New(ErrorJwtClaimsInvaild)
 	}
 
 	return claims, nil
 }
+
+// 按验证选项校验声明，每种失败返回各自的错误
+func (j *Jwt) verifyClaims(claims *Claims, now time.Time) error {
+	var (
+		opts   = j.options
+		leeway = opts.Leeway
+	)
+
+	if exp := claims.GetExpiresAt(); !exp.IsZero() && now.After(exp.Add(leeway)) {
+		return errors.New(ErrorJwtExpired)
+	}
+	if nbf := claims.GetNotBefore(); !nbf.IsZero() && now.Add(leeway).Before(nbf) {
+		return errors.New(ErrorJwtNotValidYet)
+	}
+	iat := claims.GetIssuedAt()
+	if !iat.IsZero() && now.Add(leeway).Before(iat) {
+		return errors.New(ErrorJwtIssuedInFuture)
+	}
+	if opts.MaxAge > 0 {
+		if iat.IsZero() {
+			return errors.New(ErrorJwtIssuedAtMissing)
+		}
+		if now.Sub(iat) > opts.MaxAge+leeway {
+			return errors.New(ErrorJwtTooOld)
+		}
+	}
+	if opts.Issuer != "" && claims.GetIssuer() != opts.Issuer {
+		return errors.New(ErrorJwtIssuerInvalid)
+	}
+	if len(opts.Audience) > 0 && !matchAudience(claims.GetAudience(), opts.Audience, opts.AudienceMatch) {
+		return errors.New(ErrorJwtAudienceInvalid)
+	}
+
+	return claims.validFields()
+}
+
+// 生成随机编号，用于jti以及授权家族
+func newTokenId() (string, error) {
+	var (
+		id [16]byte
+	)
+
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id[:]), nil
+}
+
+// NumericDate转为时间，nil返回零值
+func numericDateTime(date *pkg.NumericDate) time.Time {
+	if date == nil {
+		return time.Time{}
+	}
+	return date.Time
+}
+
+// 从Token中读取保留声明之外的自定义声明
+func extraClaims(ticket string) (map[string]interface{}, error) {
+	var (
+		parts  = strings.Split(ticket, ".")
+		fields map[string]interface{}
+		data   []byte
+		err    error
+	)
+
+	if len(parts) != 3 {
+		return nil, errors.New(ErrorJwtParseInvaild)
+	}
+	if data, err = pkg.DecodeSegment(parts[1]); err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for name := range fields {
+		if reservedClaims[name] {
+			delete(fields, name)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	return fields, nil
+}
+
+// 授众匹配，any要求包含任一授众，all要求包含全部授众
+func matchAudience(audience, required []string, match string) bool {
+	var (
+		matched int
+		set     = make(map[string]bool, len(audience))
+	)
+
+	for _, v := range audience {
+		set[v] = true
+	}
+	for _, v := range required {
+		if set[v] {
+			matched++
+		}
+	}
+	if match == AudienceMatchAll {
+		return matched == len(required)
+	}
+	return matched > 0
+}
+
+// 合并角色列表，去除空值与重复值
+func uniqueRoles(lists ...[]string) []string {
+	var (
+		roles []string
+		seen  = make(map[string]bool)
+	)
+
+	for _, list := range lists {
+		for _, role := range list {
+			if role != "" && !seen[role] {
+				seen[role] = true
+				roles = append(roles, role)
+			}
+		}
+	}
+
+	return roles
+}
@@ -0,0 +1,277 @@
+package rbac
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pkg "github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJwt_AsymmetricKeys(t *testing.T) {
+	var (
+		rsaKey, _     = rsa.GenerateKey(rand.Reader, 2048)
+		ecKey, _      = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		_, edKey, _   = ed25519.GenerateKey(rand.Reader)
+		signers       = map[string]crypto.Signer{"RS256": rsaKey, "ES256": ecKey, "EdDSA": edKey}
+		iClaims       = &IssueClaims{Type: "grant", Role: "admin", Subject: "uid001"}
+		expireTime, _ = time.ParseDuration("1h")
+	)
+
+	for alg, signer := range signers {
+		t.Run("TestJwt_AsymmetricKeys_"+alg, func(t *testing.T) {
+			signKey, err := NewSignerKey(signer)
+			assert.NoError(t, err)
+			assert.Equal(t, alg, signKey.Method.Alg())
+
+			ticket, err := NewJwtWithKey(signKey, "lgcgo.com").IssueToken(iClaims, expireTime)
+			assert.NoError(t, err)
+
+			// 只持有公钥的实例可以验证，但不能签发
+			verifyKey, err := NewVerifyKey(signer.Public())
+			assert.NoError(t, err)
+			verifier := NewJwtWithKey(verifyKey, "lgcgo.com")
+			claims, err := verifier.ParseToken(ticket)
+			assert.NoError(t, err)
+			assert.Equal(t, "uid001", claims.GetSubject())
+
+			_, err = verifier.IssueToken(iClaims, expireTime)
+			assert.Error(t, err)
+			assert.Equal(t, ErrorJwtVerifyOnly, err.Error())
+		})
+	}
+}
+
+func TestJwt_SigningMethodMismatch(t *testing.T) {
+	var (
+		rsaKey, _     = rsa.GenerateKey(rand.Reader, 2048)
+		iClaims       = &IssueClaims{Type: "grant", Role: "admin", Subject: "uid001"}
+		expireTime, _ = time.ParseDuration("1h")
+	)
+
+	// HMAC签发的Token不能通过RSA公钥验证
+	ticket, err := NewJwt([]byte("gVoiG1fbXf65osbjfi33MZre"), "lgcgo.com").IssueToken(iClaims, expireTime)
+	assert.NoError(t, err)
+
+	verifyKey, err := NewVerifyKey(rsaKey.Public())
+	assert.NoError(t, err)
+	_, err = NewJwtWithKey(verifyKey, "lgcgo.com").ParseToken(ticket)
+	assert.Error(t, err)
+	assert.Equal(t, ErrorJwtParseInvaild, err.Error())
+}
+
+func TestNew_PEMKeyFiles(t *testing.T) {
+	var (
+		dir            = t.TempDir()
+		privateKeyFile = filepath.Join(dir, "private.pem")
+		publicKeyFile  = filepath.Join(dir, "public.pem")
+		ecKey, _       = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	)
+
+	privateDer, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	assert.NoError(t, err)
+	publicDer, err := x509.MarshalPKIXPublicKey(ecKey.Public())
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(privateKeyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDer}), 0600))
+	assert.NoError(t, os.WriteFile(publicKeyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDer}), 0644))
+
+	issuer, err := New(Settings{TokenPrivateKeyFile: privateKeyFile, TokenIssuer: "lgcgo.com"})
+	assert.NoError(t, err)
+	token, err := issuer.Authorization("uid001", "admin")
+	assert.NoError(t, err)
+
+	verifier, err := New(Settings{TokenPublicKeyFile: publicKeyFile, TokenIssuer: "lgcgo.com"})
+	assert.NoError(t, err)
+	claims, err := verifier.VerifyToken(token.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", claims.GetRole())
+
+	_, err = verifier.Authorization("uid001", "admin")
+	assert.Error(t, err)
+	assert.Equal(t, ErrorJwtVerifyOnly, err.Error())
+}
+
+func TestJwt_KeyRotation(t *testing.T) {
+	var (
+		oldKey        = NewHMACKey([]byte("gVoiG1fbXf65osbjfi33MZre"))
+		newKey        = NewHMACKey([]byte("b8Qn3kXh0pLz4YrVw2TfJm7c"))
+		j             *Jwt
+		iClaims       = &IssueClaims{Type: "grant", Role: "admin", Subject: "uid001"}
+		expireTime, _ = time.ParseDuration("1h")
+	)
+
+	oldKey.Kid = "2022-07"
+	newKey.Kid = "2022-08"
+	j = NewJwtWithKey(oldKey, "lgcgo.com")
+
+	oldTicket, err := j.IssueToken(iClaims, expireTime)
+	assert.NoError(t, err)
+
+	t.Run("TestJwt_KeyRotation_DuplicateKid", func(t *testing.T) {
+		err := j.KeyRing().Rotate(oldKey, expireTime)
+		assert.Error(t, err)
+		assert.Equal(t, ErrorJwtKeyIdExists, err.Error())
+	})
+
+	t.Run("TestJwt_KeyRotation_RetiredKeyStillVerifies", func(t *testing.T) {
+		assert.NoError(t, j.KeyRing().Rotate(newKey, expireTime))
+		assert.Equal(t, "2022-08", j.KeyRing().Active().Kid)
+
+		newTicket, err := j.IssueToken(iClaims, expireTime)
+		assert.NoError(t, err)
+		_, err = j.ParseToken(newTicket)
+		assert.NoError(t, err)
+		_, err = j.ParseToken(oldTicket)
+		assert.NoError(t, err)
+	})
+
+	t.Run("TestJwt_KeyRotation_ActiveKeyCannotRetire", func(t *testing.T) {
+		err := j.KeyRing().Retire("2022-08", time.Now())
+		assert.Error(t, err)
+		assert.Equal(t, ErrorJwtKeyActive, err.Error())
+	})
+
+	t.Run("TestJwt_KeyRotation_ExpiredRetiredKey", func(t *testing.T) {
+		assert.NoError(t, j.KeyRing().Retire("2022-07", time.Now().Add(-time.Second)))
+		_, err := j.ParseToken(oldTicket)
+		assert.Error(t, err)
+		assert.Len(t, j.KeyRing().Keys(), 1)
+	})
+}
+
+func TestJwt_ParseTokenClaimsValidation(t *testing.T) {
+	var (
+		secret        = []byte("gVoiG1fbXf65osbjfi33MZre")
+		j             = NewJwt(secret, "lgcgo.com")
+		expireTime, _ = time.ParseDuration("1h")
+	)
+
+	t.Run("TestJwt_ParseTokenClaimsValidation_SubjectMissing", func(t *testing.T) {
+		ticket, err := j.IssueToken(&IssueClaims{Type: "grant", Role: "admin"}, expireTime)
+		assert.NoError(t, err)
+
+		_, err = j.ParseToken(ticket)
+		assert.Error(t, err)
+		assert.Equal(t, ErrorClaimsSubjectMissing, err.Error())
+	})
+
+	t.Run("TestJwt_ParseTokenClaimsValidation_RoleMistyped", func(t *testing.T) {
+		token := pkg.NewWithClaims(pkg.SigningMethodHS256, pkg.MapClaims{
+			"ist": "renew",
+			"isr": 1,
+			"sub": "uid001",
+			"exp": time.Now().Add(expireTime).Unix(),
+		})
+		ticket, err := token.SignedString(secret)
+		assert.NoError(t, err)
+
+		_, err = j.ParseToken(ticket)
+		assert.Error(t, err)
+		assert.Equal(t, ErrorJwtClaimsInvaild, err.Error())
+
+		r, _ := New(Settings{TokenSignKey: secret, TokenIssuer: "lgcgo.com"})
+		assert.NotPanics(t, func() {
+			_, err = r.RefreshAuthorization(ticket)
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestJwt_VerifyOptions(t *testing.T) {
+	var (
+		secret = []byte("gVoiG1fbXf65osbjfi33MZre")
+		j      = NewJwt(secret, "lgcgo.com")
+		now    = time.Now()
+		sign   = func(issuer string, audience []string, iat, exp time.Time) string {
+			token := pkg.NewWithClaims(pkg.SigningMethodHS256, &Claims{
+				IssueType: "grant",
+				IssueRole: "admin",
+				RegisteredClaims: pkg.RegisteredClaims{
+					Issuer:    issuer,
+					Subject:   "uid001",
+					Audience:  audience,
+					IssuedAt:  pkg.NewNumericDate(iat),
+					NotBefore: pkg.NewNumericDate(iat),
+					ExpiresAt: pkg.NewNumericDate(exp),
+				},
+			})
+			ticket, _ := token.SignedString(secret)
+			return ticket
+		}
+	)
+
+	tests := []struct {
+		name    string
+		opts    VerifyOptions
+		ticket  string
+		wantErr string
+	}{
+		{
+			name:   "Valid",
+			opts:   VerifyOptions{Issuer: "lgcgo.com"},
+			ticket: sign("lgcgo.com", nil, now, now.Add(time.Hour)),
+		},
+		{
+			name:    "Expired",
+			opts:    VerifyOptions{},
+			ticket:  sign("lgcgo.com", nil, now.Add(-time.Hour), now.Add(-time.Minute)),
+			wantErr: ErrorJwtExpired,
+		},
+		{
+			name:   "ExpiredWithinLeeway",
+			opts:   VerifyOptions{Leeway: 2 * time.Minute},
+			ticket: sign("lgcgo.com", nil, now.Add(-time.Hour), now.Add(-time.Minute)),
+		},
+		{
+			name:    "NotValidYet",
+			opts:    VerifyOptions{Leeway: time.Second},
+			ticket:  sign("lgcgo.com", nil, now.Add(time.Minute), now.Add(time.Hour)),
+			wantErr: ErrorJwtNotValidYet,
+		},
+		{
+			name:    "IssuerInvalid",
+			opts:    VerifyOptions{Issuer: "lgcgo.com"},
+			ticket:  sign("evil.com", nil, now, now.Add(time.Hour)),
+			wantErr: ErrorJwtIssuerInvalid,
+		},
+		{
+			name:   "AudienceAny",
+			opts:   VerifyOptions{Audience: []string{"web", "app"}},
+			ticket: sign("lgcgo.com", []string{"app"}, now, now.Add(time.Hour)),
+		},
+		{
+			name:    "AudienceAll",
+			opts:    VerifyOptions{Audience: []string{"web", "app"}, AudienceMatch: AudienceMatchAll},
+			ticket:  sign("lgcgo.com", []string{"app"}, now, now.Add(time.Hour)),
+			wantErr: ErrorJwtAudienceInvalid,
+		},
+		{
+			name:    "TooOld",
+			opts:    VerifyOptions{MaxAge: time.Hour},
+			ticket:  sign("lgcgo.com", nil, now.Add(-2*time.Hour), now.Add(time.Hour)),
+			wantErr: ErrorJwtTooOld,
+		},
+	}
+	for _, tt := range tests {
+		t.Run("TestJwt_VerifyOptions_"+tt.name, func(t *testing.T) {
+			j.SetVerifyOptions(tt.opts)
+			_, err := j.ParseToken(tt.ticket)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package rbac
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+
+	pkg "github.com/golang-jwt/jwt/v4"
+)
+
+// Jwt签名密钥
+// - HMAC(HS256)：签名与验证使用同一个密钥
+// - RSA(RS256)、ECDSA(ES256/ES384/ES512)、Ed25519(EdDSA)：私钥签名，公钥验证
+// 非对称密钥未设置私钥时，只能用于验证Token
+type SigningKey struct {
+	Kid       string            // 密钥ID，签发时写入Token头部的kid
+	Method    pkg.SigningMethod // 签名算法
+	SignKey   interface{}       // 签名密钥，HMAC为[]byte，非对称为crypto.Signer
+	VerifyKey interface{}       // 验证密钥，HMAC为[]byte，非对称为公钥
+}
+
+// 实例化HMAC密钥
+func NewHMACKey(secret []byte) *SigningKey {
+	return &SigningKey{
+		Method:    pkg.SigningMethodHS256,
+		SignKey:   secret,
+		VerifyKey: secret,
+	}
+}
+
+// 实例化非对称签名密钥，签名算法由公钥类型决定
+// 支持 *rsa.PrivateKey、*ecdsa.PrivateKey、ed25519.PrivateKey 以及KMS/HSM等实现了crypto.Signer的签名器
+func NewSignerKey(signer crypto.Signer) (*SigningKey, error) {
+	var (
+		method pkg.SigningMethod
+		err    error
+	)
+
+	if signer == nil {
+		return nil, errors.New(ErrorJwtKeyInvalid)
+	}
+	if method, err = publicKeyMethod(signer.Public()); err != nil {
+		return nil, err
+	}
+
+	return &SigningKey{
+		Method:    &signerMethod{SigningMethod: method},
+		SignKey:   signer,
+		VerifyKey: signer.Public(),
+	}, nil
+}
+
+// 实例化只验证密钥
+func NewVerifyKey(publicKey crypto.PublicKey) (*SigningKey, error) {
+	var (
+		method pkg.SigningMethod
+		err    error
+	)
+
+	if publicKey == nil {
+		return nil, errors.New(ErrorJwtKeyInvalid)
+	}
+	if method, err = publicKeyMethod(publicKey); err != nil {
+		return nil, err
+	}
+
+	return &SigningKey{
+		Method:    method,
+		VerifyKey: publicKey,
+	}, nil
+}
+
+// 从PEM数据读取私钥，支持PKCS#8、PKCS#1(RSA)、SEC1(EC)格式
+func ParsePrivateKeyPEM(data []byte) (*SigningKey, error) {
+	var (
+		block *pem.Block
+		key   interface{}
+		err   error
+	)
+
+	if block, _ = pem.Decode(data); block == nil {
+		return nil, errors.New(ErrorJwtKeyPEMInvalid)
+	}
+	if key, err = x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+		if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			if key, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
+				return nil, errors.New(ErrorJwtKeyPEMInvalid)
+			}
+		}
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New(ErrorJwtKeyTypeInvalid)
+	}
+
+	return NewSignerKey(signer)
+}
+
+// 从PEM数据读取公钥，支持PKIX、PKCS#1(RSA)以及X.509证书
+func ParsePublicKeyPEM(data []byte) (*SigningKey, error) {
+	var (
+		block *pem.Block
+		key   interface{}
+		cert  *x509.Certificate
+		err   error
+	)
+
+	if block, _ = pem.Decode(data); block == nil {
+		return nil, errors.New(ErrorJwtKeyPEMInvalid)
+	}
+	if key, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+		if key, err = x509.ParsePKCS1PublicKey(block.Bytes); err != nil {
+			if cert, err = x509.ParseCertificate(block.Bytes); err != nil {
+				return nil, errors.New(ErrorJwtKeyPEMInvalid)
+			}
+			key = cert.PublicKey
+		}
+	}
+
+	return NewVerifyKey(key)
+}
+
+// 从PEM文件读取私钥
+func LoadPrivateKeyFile(path string) (*SigningKey, error) {
+	var (
+		data []byte
+		err  error
+	)
+
+	if data, err = os.ReadFile(path); err != nil {
+		return nil, err
+	}
+
+	return ParsePrivateKeyPEM(data)
+}
+
+// 从PEM文件读取公钥
+func LoadPublicKeyFile(path string) (*SigningKey, error) {
+	var (
+		data []byte
+		err  error
+	)
+
+	if data, err = os.ReadFile(path); err != nil {
+		return nil, err
+	}
+
+	return ParsePublicKeyPEM(data)
+}
+
+// 是否可以签名
+func (k *SigningKey) CanSign() bool {
+	if k.SignKey == nil {
+		return false
+	}
+	// 空的HMAC密钥不允许签名
+	if secret, ok := k.SignKey.([]byte); ok {
+		return len(secret) > 0
+	}
+	return true
+}
+
+// 根据公钥类型获取签名算法
+func publicKeyMethod(publicKey crypto.PublicKey) (pkg.SigningMethod, error) {
+	switch k := publicKey.(type) {
+	case *rsa.PublicKey:
+		return pkg.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve.Params().Name {
+		case "P-256":
+			return pkg.SigningMethodES256, nil
+		case "P-384":
+			return pkg.SigningMethodES384, nil
+		case "P-521":
+			return pkg.SigningMethodES512, nil
+		}
+	case ed25519.PublicKey:
+		return pkg.SigningMethodEdDSA, nil
+	}
+
+	return nil, errors.New(ErrorJwtKeyTypeInvalid)
+}
+
+// 基于crypto.Signer的签名算法
+// 签名交由signer完成（私钥可以不出HSM/KMS），验证仍使用标准算法
+type signerMethod struct {
+	pkg.SigningMethod
+}
+
+// 签名
+func (m *signerMethod) Sign(signingString string, key interface{}) (string, error) {
+	var (
+		signer crypto.Signer
+		hash   crypto.Hash
+		digest []byte
+		sig    []byte
+		err    error
+		ok     bool
+	)
+
+	if signer, ok = key.(crypto.Signer); !ok {
+		return "", pkg.ErrInvalidKeyType
+	}
+	// Ed25519对原文签名，其余算法对摘要签名
+	switch method := m.SigningMethod.(type) {
+	case *pkg.SigningMethodRSA:
+		hash = method.Hash
+	case *pkg.SigningMethodECDSA:
+		hash = method.Hash
+	}
+	if hash == 0 {
+		digest = []byte(signingString)
+	} else {
+		hasher := hash.New()
+		hasher.Write([]byte(signingString))
+		digest = hasher.Sum(nil)
+	}
+	if sig, err = signer.Sign(rand.Reader, digest, hash); err != nil {
+		return "", err
+	}
+	// ECDSA签名器输出ASN.1格式，Jwt要求 r||s 定长格式
+	if method, ok := m.SigningMethod.(*pkg.SigningMethodECDSA); ok {
+		if sig, err = ecdsaRawSignature(sig, method.KeySize); err != nil {
+			return "", err
+		}
+	}
+
+	return pkg.EncodeSegment(sig), nil
+}
+
+// ASN.1格式的ECDSA签名转为 r||s 定长格式
+func ecdsaRawSignature(der []byte, keySize int) ([]byte, error) {
+	var (
+		sig struct {
+			R, S *big.Int
+		}
+		out = make([]byte, 2*keySize)
+	)
+
+	if _, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, err
+	}
+	sig.R.FillBytes(out[:keySize])
+	sig.S.FillBytes(out[keySize:])
+
+	return out, nil
+}
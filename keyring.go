@@ -0,0 +1,222 @@
+package rbac
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 签名密钥环
+// - 启用密钥：签发新的Token，其kid写入Token头部
+// - 其它密钥：只用于验证，退役的密钥到期后自动移除
+// 密钥环是并发安全的，可以在运行时添加、轮换、退役密钥
+type KeyRing struct {
+	mu      sync.RWMutex
+	active  string                 // 启用密钥的kid
+	keys    map[string]*SigningKey // 全部密钥，kid => 密钥
+	retired map[string]time.Time   // 退役密钥，kid => 验证截止时间
+}
+
+// 实例化密钥环，key可以签名时设为启用密钥
+func NewKeyRing(key *SigningKey) *KeyRing {
+	ring := &KeyRing{
+		keys:    make(map[string]*SigningKey),
+		retired: make(map[string]time.Time),
+	}
+	if key != nil {
+		ring.keys[key.Kid] = key
+		if key.CanSign() {
+			ring.active = key.Kid
+		}
+	}
+
+	return ring
+}
+
+// 获取启用密钥，没有可签名的密钥时返回nil
+func (k *KeyRing) Active() *SigningKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if key, ok := k.keys[k.active]; ok && key.CanSign() {
+		return key
+	}
+	return nil
+}
+
+// 根据kid获取验证密钥，已过验证截止时间的退役密钥视为不存在
+func (k *KeyRing) Key(kid string) (*SigningKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, false
+	}
+	if until, ok := k.retired[kid]; ok && time.Now().After(until) {
+		return nil, false
+	}
+	return key, true
+}
+
+// 获取全部有效密钥，按kid排序
+func (k *KeyRing) Keys() []*SigningKey {
+	var (
+		keys []*SigningKey
+	)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.prune()
+	for _, key := range k.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Kid < keys[j].Kid
+	})
+
+	return keys
+}
+
+// 添加验证密钥，不改变启用密钥
+func (k *KeyRing) AddKey(key *SigningKey) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.add(key)
+}
+
+// 启用指定的密钥签发新的Token，原启用密钥继续用于验证
+func (k *KeyRing) Activate(kid string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key, ok := k.keys[kid]
+	if !ok {
+		return errors.New(ErrorJwtKeyNotFound)
+	}
+	if !key.CanSign() {
+		return errors.New(ErrorJwtVerifyOnly)
+	}
+	delete(k.retired, kid)
+	k.active = kid
+
+	return nil
+}
+
+// 轮换密钥：添加并启用新密钥，原启用密钥退役，在retain时间内继续用于验证
+func (k *KeyRing) Rotate(key *SigningKey, retain time.Duration) error {
+	var (
+		previous string
+		err      error
+	)
+
+	if key == nil || !key.CanSign() {
+		return errors.New(ErrorJwtVerifyOnly)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if err = k.add(key); err != nil {
+		return err
+	}
+	previous = k.active
+	k.active = key.Kid
+	if _, ok := k.keys[previous]; ok {
+		k.retired[previous] = time.Now().Add(retain)
+	}
+
+	return nil
+}
+
+// 退役密钥，until之前签发的Token仍可验证；启用中的密钥不能退役
+func (k *KeyRing) Retire(kid string, until time.Time) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, ok := k.keys[kid]; !ok {
+		return errors.New(ErrorJwtKeyNotFound)
+	}
+	if kid == k.active {
+		return errors.New(ErrorJwtKeyActive)
+	}
+	k.retired[kid] = until
+
+	return nil
+}
+
+// 立即移除密钥，使用该密钥签发的Token全部失效；启用中的密钥不能移除
+func (k *KeyRing) Remove(kid string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, ok := k.keys[kid]; !ok {
+		return errors.New(ErrorJwtKeyNotFound)
+	}
+	if kid == k.active {
+		return errors.New(ErrorJwtKeyActive)
+	}
+	delete(k.keys, kid)
+	delete(k.retired, kid)
+
+	return nil
+}
+
+// 替换全部密钥，用于从JWKS重新加载；启用密钥仍存在且可签名时保持启用
+func (k *KeyRing) Replace(keys []*SigningKey) error {
+	var (
+		replaced = make(map[string]*SigningKey)
+	)
+
+	for _, key := range keys {
+		if key == nil || key.Method == nil || key.VerifyKey == nil {
+			return errors.New(ErrorJwtKeyInvalid)
+		}
+		if _, ok := replaced[key.Kid]; ok {
+			return errors.New(ErrorJwtKeyIdExists)
+		}
+		replaced[key.Kid] = key
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if key, ok := replaced[k.active]; !ok || !key.CanSign() {
+		k.active = ""
+	}
+	k.keys = replaced
+	k.retired = make(map[string]time.Time)
+
+	return nil
+}
+
+// 添加密钥，调用方需持有写锁
+func (k *KeyRing) add(key *SigningKey) error {
+	if key == nil || key.Method == nil || key.VerifyKey == nil {
+		return errors.New(ErrorJwtKeyInvalid)
+	}
+	k.prune()
+	if _, ok := k.keys[key.Kid]; ok {
+		return errors.New(ErrorJwtKeyIdExists)
+	}
+	k.keys[key.Kid] = key
+
+	return nil
+}
+
+// 移除已过验证截止时间的退役密钥，调用方需持有写锁
+func (k *KeyRing) prune() {
+	var (
+		now = time.Now()
+	)
+
+	for kid, until := range k.retired {
+		if now.After(until) {
+			delete(k.keys, kid)
+			delete(k.retired, kid)
+		}
+	}
+}
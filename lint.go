@@ -0,0 +1,417 @@
+package rbac
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// 政策问题级别
+const (
+	LintError   = "error"   // 错误，阻止保存与加载政策
+	LintWarning = "warning" // 警告，只报告不阻止，严格模式下视为错误
+)
+
+// 默认支持的请求方法
+var lintMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS", "CONNECT", "TRACE"}
+
+// 政策检查选项
+type PolicyLintOptions struct {
+	Disabled  bool     // 关闭保存与重新加载前的自动检查
+	Strict    bool     // 严格模式，警告同样阻止保存与加载
+	Domains   []string // 已知的域，设置后其它域视为错误；为空时没有资源访问政策的域视为警告
+	Methods   []string // 支持的请求方法，默认为标准的HTTP方法
+	PathMatch string   // 路径匹配方式，Casbin检查时默认使用 Casbin.PathMatch
+	SuperRole string   // 挂载顶级角色的超级管理员角色名称，默认为 root；Casbin检查时默认使用 Casbin.SuperRole
+}
+
+// 政策问题
+type PolicyIssue struct {
+	Line     int    // 行号，从1开始；检查政策集合时按保存的顺序，先资源访问政策后角色关系政策
+	Text     string // 行内容
+	Err      string // 问题描述，如 ErrorPolicyRoleCycle
+	Severity string // 级别，LintError 或 LintWarning
+	Related  int    // 相关的行号，如重复或覆盖该行的政策，0为没有
+}
+
+func (i *PolicyIssue) Error() string {
+	if i.Related > 0 {
+		return fmt.Sprintf("policy line %d: %s (line %d)", i.Line, i.Err, i.Related)
+	}
+	return fmt.Sprintf("policy line %d: %s", i.Line, i.Err)
+}
+
+// 政策检查错误，包含全部问题
+type PolicyLintError struct {
+	Issues []PolicyIssue
+}
+
+func (e *PolicyLintError) Error() string {
+	if len(e.Issues) == 1 {
+		return ErrorPolicyLintFailed + ": " + e.Issues[0].Error()
+	}
+	return fmt.Sprintf("%s: %s (and %d more)", ErrorPolicyLintFailed, e.Issues[0].Error(), len(e.Issues)-1)
+}
+
+// 检查的政策行
+type lintEntry struct {
+	line    int
+	text    string
+	up      *UriPolicy
+	rp      *RolePolicy
+	effect  string       // 资源访问政策的效果
+	pattern *lintPattern // 资源访问政策预编译的路径模式
+}
+
+// 检查政策集合，行号按SaveAllPolicyCsv写入的顺序
+func LintPolicies(ups []UriPolicy, rps []RolePolicy, opts PolicyLintOptions) []PolicyIssue {
+	var (
+		entries []lintEntry
+	)
+
+	for i := range ups {
+		entries = append(entries, lintEntry{line: len(entries) + 1, text: ups[i].FormatLine(), up: &ups[i]})
+	}
+	for i := range rps {
+		entries = append(entries, lintEntry{line: len(entries) + 1, text: rps[i].formatLine(opts.root()), rp: &rps[i]})
+	}
+
+	return lintEntries(entries, opts)
+}
+
+// 检查政策文件，格式错误的行同样作为问题报告
+func LintPolicyFile(path string, opts PolicyLintOptions) ([]PolicyIssue, error) {
+	var (
+		entries []lintEntry
+		issues  []PolicyIssue
+		number  int
+	)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		number++
+		p, err := parsePolicyLine(scanner.Text(), opts.root())
+		if err != nil {
+			issues = append(issues, PolicyIssue{Line: number, Text: scanner.Text(), Err: err.Error(), Severity: LintError})
+			continue
+		}
+		switch v := p.(type) {
+		case *UriPolicy:
+			entries = append(entries, lintEntry{line: number, text: scanner.Text(), up: v})
+		case *RolePolicy:
+			entries = append(entries, lintEntry{line: number, text: scanner.Text(), rp: v})
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return append(issues, lintEntries(entries, opts)...), nil
+}
+
+// 获取挂载顶级角色的超级管理员主体
+func (o *PolicyLintOptions) root() string {
+	if o.SuperRole == "" {
+		return rootSubject
+	}
+	return roleSubject(o.SuperRole)
+}
+
+// 返回阻止保存与加载的问题，没有时返回nil
+func lintFailed(issues []PolicyIssue, strict bool) error {
+	var (
+		failed []PolicyIssue
+	)
+
+	for _, v := range issues {
+		if v.Severity == LintError || strict {
+			failed = append(failed, v)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return &PolicyLintError{Issues: failed}
+}
+
+// 检查政策行
+func lintEntries(entries []lintEntry, opts PolicyLintOptions) []PolicyIssue {
+	var (
+		methods  = opts.Methods
+		issues   []PolicyIssue
+		seen     = make(map[string]int)  // 政策规则所在的行号
+		domains  = make(map[string]bool) // 有资源访问政策的域
+		owners   = make(map[string]bool) // 有资源访问政策的角色，键为 域/角色
+		children = make(map[string][]string)
+		groups   = make(map[string][]int)        // 资源访问政策在entries中的位置，键为 域/角色
+		patterns = make(map[string]*lintPattern) // 预编译的路径模式，键为政策路径
+	)
+
+	if len(methods) == 0 {
+		methods = lintMethods
+	}
+	report := func(e lintEntry, err, severity string, related int) {
+		issues = append(issues, PolicyIssue{Line: e.line, Text: e.text, Err: err, Severity: severity, Related: related})
+	}
+
+	for i, e := range entries {
+		if e.up != nil {
+			domains[e.up.Domain] = true
+			owners[e.up.Domain+"/"+e.up.Role] = true
+			groups[e.up.Domain+"/"+e.up.Role] = append(groups[e.up.Domain+"/"+e.up.Role], i)
+			if _, ok := patterns[e.up.Path]; !ok {
+				patterns[e.up.Path] = compileLintPattern(opts.PathMatch, e.up.Path)
+			}
+			entries[i].effect = e.up.rule()[4]
+			entries[i].pattern = patterns[e.up.Path]
+		}
+		if e.rp != nil && e.rp.ParentRole != "" {
+			key := e.rp.Domain + "/" + e.rp.ParentRole
+			children[key] = append(children[key], e.rp.Role)
+		}
+	}
+
+	for i, e := range entries {
+		var (
+			key string
+		)
+
+		if e.up != nil {
+			key = "p/" + strings.Join(e.up.rule(), "/")
+			if strings.HasPrefix(e.up.Role, rolePrefix) {
+				report(e, ErrorPolicyRolePrefixed, LintError, 0)
+			}
+			if len(opts.Domains) > 0 && !containsString(opts.Domains, e.up.Domain) {
+				report(e, ErrorPolicyDomainUnknown, LintError, 0)
+			}
+			if !lintMethod(e.up.Method, methods) {
+				report(e, ErrorPolicyMethodInvalid, LintError, 0)
+			}
+			if !lintPath(opts.PathMatch, e.up.Path) {
+				report(e, ErrorPolicyPathInvalid, LintError, 0)
+			}
+			// 被同一角色的其它政策覆盖的政策
+			for _, j := range groups[e.up.Domain+"/"+e.up.Role] {
+				if i != j && lintShadowed(&entries[i], &entries[j]) {
+					report(e, ErrorPolicyShadowed, LintWarning, entries[j].line)
+					break
+				}
+			}
+		}
+
+		if e.rp != nil {
+			key = "g/" + strings.Join(e.rp.rule(opts.root()), "/")
+			if strings.HasPrefix(e.rp.Role, rolePrefix) || strings.HasPrefix(e.rp.ParentRole, rolePrefix) {
+				report(e, ErrorPolicyRolePrefixed, LintError, 0)
+			}
+			if len(opts.Domains) > 0 && !containsString(opts.Domains, e.rp.Domain) {
+				report(e, ErrorPolicyDomainUnknown, LintError, 0)
+			} else if len(opts.Domains) == 0 && !domains[e.rp.Domain] {
+				report(e, ErrorPolicyDomainUnknown, LintWarning, 0)
+			}
+			if e.rp.ParentRole != "" {
+				// 角色能到达父级角色即形成循环
+				parent := e.rp.ParentRole
+				if lintWalk(children, e.rp.Domain, e.rp.Role, func(role string) bool { return role == parent }) {
+					report(e, ErrorPolicyRoleCycle, LintError, 0)
+				}
+				// 父级角色以及继承的角色都没有资源访问政策
+				if !lintWalk(children, e.rp.Domain, parent, func(role string) bool { return owners[e.rp.Domain+"/"+role] }) {
+					report(e, ErrorPolicyRoleEmpty, LintWarning, 0)
+				}
+			}
+		}
+
+		if line, ok := seen[key]; ok {
+			report(e, ErrorPolicyDuplicate, LintWarning, line)
+		} else if key != "" {
+			seen[key] = e.line
+		}
+	}
+
+	return issues
+}
+
+// 检查请求方法，支持通配符 * 以及使用 | 分隔的多个方法
+func lintMethod(method string, methods []string) bool {
+	if method == methodWildcard {
+		return true
+	}
+	for _, v := range strings.Split(method, "|") {
+		if !containsString(methods, strings.TrimSpace(v)) {
+			return false
+		}
+	}
+	return true
+}
+
+// 检查资源路径，正则表达式必须可以编译，其它匹配方式必须以 / 开头且不包含空白字符
+func lintPath(pathMatch, path string) bool {
+	if pathMatch == PathMatchRegex {
+		_, err := regexp.Compile(path)
+		return err == nil
+	}
+	return strings.HasPrefix(path, "/") && !strings.ContainsAny(path, " \t\r\n") && !strings.Contains(path, "//")
+}
+
+// 判断政策是否被同一角色的另一条政策覆盖：效果相同，或被拒绝政策覆盖的允许政策
+func lintShadowed(e, other *lintEntry) bool {
+	var (
+		p = e.up
+		o = other.up
+	)
+
+	if p.Role != o.Role || p.Domain != o.Domain || *p == *o {
+		return false
+	}
+	if e.effect != other.effect && (e.effect != EffectAllow || other.effect != EffectDeny) {
+		return false
+	}
+	// 相同规则由重复检查报告
+	if e.effect == other.effect && p.Path == o.Path && p.Method == o.Method {
+		return false
+	}
+	// 正则表达式之间无法判断覆盖关系，只比较相同的路径
+	if p.Path != o.Path {
+		if e.pattern == nil || other.pattern == nil || !other.pattern.match(p.Path) {
+			return false
+		}
+		// 互相匹配的路径模式(如 /users/:id 与 /users/*)无法判断范围，不视为覆盖
+		if e.pattern.match(o.Path) {
+			return false
+		}
+	}
+	if o.Method == methodWildcard {
+		return true
+	}
+	if p.Method == methodWildcard {
+		return false
+	}
+	for _, v := range strings.Split(p.Method, "|") {
+		if !methodMatch(strings.TrimSpace(v), o.Method) {
+			return false
+		}
+	}
+	return true
+}
+
+// 预编译的RESTful路径模式，与Casbin的keyMatch2、keyMatch4一致
+// 覆盖检查需要两两比较政策，每个路径模式只编译一次
+type lintPattern struct {
+	prefix string         // 固定的前缀，用于快速排除
+	suffix string         // 固定的后缀，用于快速排除
+	re     *regexp.Regexp // 匹配路径的正则表达式
+	tokens []string       // keyMatch4的参数名称，相同名称的参数值必须相同
+}
+
+var (
+	lintKeyMatch2Re = regexp.MustCompile(`:[^/]+`)
+	lintKeyMatch4Re = regexp.MustCompile(`{([^/]+)}`)
+)
+
+// 编译路径模式，只支持keyMatch2与keyMatch4，其它匹配方式或无法编译时返回nil
+func compileLintPattern(pathMatch, path string) *lintPattern {
+	var (
+		p    = &lintPattern{}
+		expr = strings.Replace(path, "/*", "/.*", -1)
+		err  error
+	)
+
+	switch pathMatch {
+	case PathMatchKeyMatch2:
+		expr = lintKeyMatch2Re.ReplaceAllString(expr, "[^/]+")
+	case PathMatchKeyMatch4:
+		expr = lintKeyMatch4Re.ReplaceAllStringFunc(expr, func(s string) string {
+			p.tokens = append(p.tokens, s[1:len(s)-1])
+			return "([^/]+)"
+		})
+	default:
+		return nil
+	}
+	if p.re, err = regexp.Compile("^" + expr + "$"); err != nil {
+		return nil
+	}
+	p.prefix, p.suffix = literalAffixes(expr)
+
+	return p
+}
+
+// 获取正则表达式开头与结尾的固定字符串，匹配的路径必须以其开头与结尾
+func literalAffixes(expr string) (string, string) {
+	var (
+		prefix, suffix []rune
+	)
+
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return "", ""
+	}
+	subs := []*syntax.Regexp{re}
+	if re.Op == syntax.OpConcat {
+		subs = re.Sub
+	}
+	literal := func(v *syntax.Regexp) bool {
+		return v.Op == syntax.OpLiteral && v.Flags&syntax.FoldCase == 0
+	}
+	for i := 0; i < len(subs) && literal(subs[i]); i++ {
+		prefix = append(prefix, subs[i].Rune...)
+	}
+	for i := len(subs) - 1; i >= 0 && literal(subs[i]); i-- {
+		suffix = append(append([]rune{}, subs[i].Rune...), suffix...)
+	}
+	return string(prefix), string(suffix)
+}
+
+// 判断路径是否匹配模式
+func (p *lintPattern) match(path string) bool {
+	if !strings.HasPrefix(path, p.prefix) || !strings.HasSuffix(path, p.suffix) {
+		return false
+	}
+	if len(p.tokens) == 0 {
+		return p.re.MatchString(path)
+	}
+	matches := p.re.FindStringSubmatch(path)
+	if len(matches) != len(p.tokens)+1 {
+		return false
+	}
+	values := make(map[string]string)
+	for i, token := range p.tokens {
+		if v, ok := values[token]; ok && v != matches[i+1] {
+			return false
+		}
+		values[token] = matches[i+1]
+	}
+	return true
+}
+
+// 在域中由角色经角色关系遍历自身与全部继承的角色，found返回true时停止遍历并返回true
+func lintWalk(children map[string][]string, domain, role string, found func(role string) bool) bool {
+	var (
+		visited = map[string]bool{role: true}
+		queue   = []string{role}
+	)
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if found(current) {
+			return true
+		}
+		for _, v := range children[domain+"/"+current] {
+			if !visited[v] {
+				visited[v] = true
+				queue = append(queue, v)
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,262 @@
+package rbac
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	fileadapter "github.com/casbin/casbin/v2/persist/file-adapter"
+	"github.com/stretchr/testify/assert"
+)
+
+// 获取问题描述与行号
+func lintIssueLines(issues []PolicyIssue, err string) []int {
+	var (
+		lines []int
+	)
+
+	for _, v := range issues {
+		if v.Err == err {
+			lines = append(lines, v.Line)
+		}
+	}
+	return lines
+}
+
+func TestLintPolicyFile(t *testing.T) {
+	var (
+		path  = filepath.Join(t.TempDir(), "policy.csv")
+		lines = "p, role::admin, default, /users, GET\n" + // 1
+			"p, role::admin, default, /users, GET\n" + // 2 重复
+			"p, role::admin, default, /users, FETCH\n" + // 3 不支持的方法
+			"p, role::admin, default, users, GET\n" + // 4 路径格式错误
+			"p, role::role::admin, default, /users, POST\n" + // 5 重复前缀
+			"p, role::editor, default, /article, *\n" + // 6
+			"p, role::editor, default, /article, GET|POST\n" + // 7 被第6行覆盖
+			"p, role::editor, default, /article, PUT, deny\n" + // 8
+			"p, role::editor, default, /article, PUT\n" + // 9 被第8行的拒绝政策覆盖
+			"g, root, role::admin, default\n" + // 10
+			"g, role::a, role::b, default\n" + // 11 循环
+			"g, role::b, role::a, default\n" + // 12 循环
+			"g, role::empty, role::nothing, default\n" + // 13 父级角色没有权限
+			"g, root, role::admin, manager\n" + // 14 没有政策的域
+			"x, role::admin\n" + // 15 格式错误
+			"\n" +
+			"# comment\n"
+		issues []PolicyIssue
+		err    error
+	)
+
+	assert.NoError(t, os.WriteFile(path, []byte(lines), 0644))
+	issues, err = LintPolicyFile(path, PolicyLintOptions{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []int{2}, lintIssueLines(issues, ErrorPolicyDuplicate))
+	assert.Equal(t, []int{3}, lintIssueLines(issues, ErrorPolicyMethodInvalid))
+	assert.Equal(t, []int{4}, lintIssueLines(issues, ErrorPolicyPathInvalid))
+	assert.Equal(t, []int{5}, lintIssueLines(issues, ErrorPolicyRolePrefixed))
+	assert.Equal(t, []int{7, 9}, lintIssueLines(issues, ErrorPolicyShadowed))
+	assert.Equal(t, []int{11, 12}, lintIssueLines(issues, ErrorPolicyRoleCycle))
+	assert.Equal(t, []int{11, 12, 13}, lintIssueLines(issues, ErrorPolicyRoleEmpty))
+	assert.Equal(t, []int{14}, lintIssueLines(issues, ErrorPolicyDomainUnknown))
+	assert.Equal(t, []int{15}, lintIssueLines(issues, ErrorPolicyTypeInvalid))
+
+	for _, v := range issues {
+		switch v.Err {
+		case ErrorPolicyDuplicate:
+			assert.Equal(t, LintWarning, v.Severity)
+			assert.Equal(t, 1, v.Related)
+			assert.Equal(t, "policy line 2: policy duplicate (line 1)", v.Error())
+		case ErrorPolicyShadowed:
+			assert.Equal(t, LintWarning, v.Severity)
+		case ErrorPolicyRoleCycle, ErrorPolicyMethodInvalid, ErrorPolicyPathInvalid:
+			assert.Equal(t, LintError, v.Severity)
+		}
+	}
+
+	_, err = LintPolicyFile(filepath.Join(t.TempDir(), "missing.csv"), PolicyLintOptions{})
+	assert.Error(t, err)
+}
+
+func TestLintPolicies(t *testing.T) {
+	var (
+		ups = []UriPolicy{
+			{Role: "reader", Domain: "default", Path: "/users/1", Method: "GET"},
+			{Role: "reader", Domain: "default", Path: "/users/*", Method: "GET"},
+			{Role: "reader", Domain: "www", Path: "/users", Method: "GET"},
+			{Role: "auditor", Domain: "default", Path: "^/logs/[0-9]+$", Method: "GET"},
+		}
+		rps = []RolePolicy{
+			{Role: "reader", Domain: "default"},
+			{Role: "reader", Domain: "www"},
+		}
+	)
+
+	t.Run("TestLintPolicies_Domains", func(t *testing.T) {
+		issues := LintPolicies(ups, rps, PolicyLintOptions{Domains: []string{"default"}, PathMatch: PathMatchKeyMatch2})
+		// 行号按保存的顺序，先资源访问政策后角色关系政策
+		assert.Equal(t, []int{3, 6}, lintIssueLines(issues, ErrorPolicyDomainUnknown))
+		assert.Equal(t, []int{1}, lintIssueLines(issues, ErrorPolicyShadowed))
+		assert.Equal(t, []int{4}, lintIssueLines(issues, ErrorPolicyPathInvalid))
+	})
+
+	t.Run("TestLintPolicies_Regex", func(t *testing.T) {
+		issues := LintPolicies(ups[3:], nil, PolicyLintOptions{PathMatch: PathMatchRegex})
+		assert.Empty(t, issues)
+
+		issues = LintPolicies([]UriPolicy{{Role: "a", Domain: "default", Path: "^/logs/[0-9+$", Method: "GET"}}, nil, PolicyLintOptions{PathMatch: PathMatchRegex})
+		assert.Equal(t, []int{1}, lintIssueLines(issues, ErrorPolicyPathInvalid))
+	})
+
+	t.Run("TestLintPolicies_Methods", func(t *testing.T) {
+		issues := LintPolicies([]UriPolicy{{Role: "a", Domain: "default", Path: "/", Method: "PURGE"}}, nil, PolicyLintOptions{Methods: []string{"GET", "PURGE"}})
+		assert.Empty(t, issues)
+	})
+
+	t.Run("TestLintPolicies_Example", func(t *testing.T) {
+		issues, err := LintPolicyFile("examples/policy.csv", PolicyLintOptions{})
+		assert.NoError(t, err)
+		assert.NoError(t, lintFailed(issues, false))
+	})
+}
+
+func TestLintPattern(t *testing.T) {
+	var (
+		cases = []struct {
+			pathMatch, path, pattern string
+		}{
+			{PathMatchKeyMatch2, "/users/1", "/users/:id"},
+			{PathMatchKeyMatch2, "/users/1/posts", "/users/:id"},
+			{PathMatchKeyMatch2, "/users/1/posts", "/users/:id/posts"},
+			{PathMatchKeyMatch2, "/files/a/b.txt", "/files/*"},
+			{PathMatchKeyMatch2, "/files", "/files/*"},
+			{PathMatchKeyMatch2, "/users/:id", "/users/1"},
+			{PathMatchKeyMatch4, "/parent/1/child/1", "/parent/{id}/child/{id}"},
+			{PathMatchKeyMatch4, "/parent/1/child/2", "/parent/{id}/child/{id}"},
+			{PathMatchKeyMatch4, "/parent/1/child/2", "/parent/{id}/child/{cid}"},
+			{PathMatchKeyMatch4, "/files/a/b", "/files/*"},
+		}
+	)
+
+	// 预编译的路径模式与Casbin的匹配函数结果一致
+	for _, v := range cases {
+		want, err := matchPath(v.pathMatch, v.path, v.pattern)
+		assert.NoError(t, err)
+		assert.Equal(t, want, compileLintPattern(v.pathMatch, v.pattern).match(v.path), v)
+	}
+	assert.Nil(t, compileLintPattern(PathMatchRegex, "^/users$"))
+}
+
+func TestCasbin_PolicyLint(t *testing.T) {
+	var (
+		path = filepath.Join(t.TempDir(), "policy.csv")
+		good = "p, role::reader, default, /article, GET\n"
+		bad  = "p, role::reader, default, /article, GET\n" +
+			"g, role::a, role::b, default\n" +
+			"g, role::b, role::a, default\n"
+		c       *Casbin
+		lintErr *PolicyLintError
+		err     error
+	)
+
+	assert.NoError(t, os.WriteFile(path, []byte(good), 0644))
+	c = NewCasbin(path)
+	assert.NoError(t, c.Init())
+
+	t.Run("TestCasbin_PolicyLint_Reload", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(path, []byte(bad), 0644))
+		err = c.Reload()
+		assert.True(t, errors.As(err, &lintErr))
+		assert.Equal(t, []int{2, 3}, lintIssueLines(lintErr.Issues, ErrorPolicyRoleCycle))
+		// 继续使用原有的授权政策
+		ps, err := c.ListRolePolicies(nil)
+		assert.NoError(t, err)
+		assert.Empty(t, ps)
+		assert.NoError(t, os.WriteFile(path, []byte(good), 0644))
+	})
+
+	t.Run("TestCasbin_PolicyLint_Save", func(t *testing.T) {
+		err = c.SaveAllPolicyCsv(
+			[]UriPolicy{{Role: "reader", Domain: "default", Path: "/article", Method: "FETCH"}},
+			nil,
+		)
+		assert.True(t, errors.As(err, &lintErr))
+		assert.Equal(t, []int{1}, lintIssueLines(lintErr.Issues, ErrorPolicyMethodInvalid))
+		// 政策文件不变
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, good, string(data))
+	})
+
+	t.Run("TestCasbin_PolicyLint_Strict", func(t *testing.T) {
+		var (
+			ups = []UriPolicy{
+				{Role: "reader", Domain: "default", Path: "/article", Method: "GET"},
+				{Role: "reader", Domain: "default", Path: "/article", Method: "GET"},
+			}
+		)
+
+		c.PolicyLint.Strict = true
+		defer func() { c.PolicyLint.Strict = false }()
+		err = c.SaveAllPolicyCsv(ups, nil)
+		assert.True(t, errors.As(err, &lintErr))
+		assert.Equal(t, []int{2}, lintIssueLines(lintErr.Issues, ErrorPolicyDuplicate))
+
+		c.PolicyLint.Strict = false
+		assert.Equal(t, []int{2}, lintIssueLines(c.LintPolicies(ups, nil), ErrorPolicyDuplicate))
+	})
+
+	t.Run("TestCasbin_PolicyLint_Adapter", func(t *testing.T) {
+		var (
+			other = filepath.Join(t.TempDir(), "policy.csv")
+			ac    = NewCasbin("")
+		)
+
+		assert.NoError(t, os.WriteFile(other, []byte(bad), 0644))
+		ac.SetAdapter(fileadapter.NewAdapter(other))
+		assert.NoError(t, ac.Init())
+		err = ac.Reload()
+		assert.True(t, errors.As(err, &lintErr))
+		assert.Equal(t, ErrorPolicyRoleCycle, lintErr.Issues[0].Err)
+
+		ac.PolicyLint.Disabled = true
+		assert.NoError(t, ac.Reload())
+	})
+
+	t.Run("TestCasbin_PolicyLint_InitialLoad", func(t *testing.T) {
+		var (
+			other = filepath.Join(t.TempDir(), "policy.csv")
+		)
+
+		// 首次加载不检查，已有的政策文件不会导致启动失败
+		assert.NoError(t, os.WriteFile(other, []byte("p, role::a, default, /a, get\n"), 0644))
+		r, err := New(Settings{
+			TokenSignKey:   []byte("gVoiG1fbXf65osbjfi33MZre"),
+			PolicyFilePath: other,
+		})
+		assert.NoError(t, err)
+		err = r.Reload()
+		assert.True(t, errors.As(err, &lintErr))
+		assert.Equal(t, ErrorPolicyMethodInvalid, lintErr.Issues[0].Err)
+	})
+}
+
+// 同一角色的大量RESTful政策，覆盖检查每个路径模式只编译一次
+func BenchmarkLintPolicies_KeyMatch2(b *testing.B) {
+	var (
+		ups []UriPolicy
+	)
+
+	for i := 0; i < 2000; i++ {
+		ups = append(ups, UriPolicy{Role: "a", Domain: "default", Path: fmt.Sprintf("/resource/:id/item%d", i), Method: "GET"})
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if issues := LintPolicies(ups, nil, PolicyLintOptions{PathMatch: PathMatchKeyMatch2}); len(issues) > 0 {
+			b.Fatal(issues[0].Error())
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package rbac
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/casbin/casbin/v2/util"
+)
+
+// 路径匹配方式
+const (
+	PathMatchExact     = "exact"     // 完全相等(默认)
+	PathMatchKeyMatch2 = "keyMatch2" // RESTful路径，支持 /users/:id 与 /files/*
+	PathMatchKeyMatch4 = "keyMatch4" // RESTful路径，支持 /users/{id} 与 /files/*，相同名称的参数值必须相同
+	PathMatchRegex     = "regex"     // 正则表达式，如 ^/users/[0-9]+$
+)
+
+// 路径匹配函数，对应模型中的 pathMatch(r.obj, p.obj)
+var pathMatchFuncs = map[string]func(args ...interface{}) (interface{}, error){
+	PathMatchExact:     exactMatchFunc,
+	PathMatchKeyMatch2: util.KeyMatch2Func,
+	PathMatchKeyMatch4: util.KeyMatch4Func,
+	PathMatchRegex:     util.RegexMatchFunc,
+}
+
+// 方法通配符
+const methodWildcard = "*"
+
+// 判断请求方法是否匹配政策方法
+// 政策方法支持通配符 * 以及使用 | 分隔的多个方法，如 GET|POST
+func methodMatch(method, pattern string) bool {
+	if pattern == methodWildcard {
+		return true
+	}
+	for _, v := range strings.Split(pattern, "|") {
+		if strings.TrimSpace(v) == method {
+			return true
+		}
+	}
+	return false
+}
+
+// 方法匹配函数，对应模型中的 methodMatch(r.act, p.act)
+func methodMatchFunc(args ...interface{}) (interface{}, error) {
+	method, pattern, err := matchArgs("methodMatch", args)
+	if err != nil {
+		return false, err
+	}
+	return methodMatch(method, pattern), nil
+}
+
+// 完全相等的路径匹配函数
+func exactMatchFunc(args ...interface{}) (interface{}, error) {
+	path, pattern, err := matchArgs("pathMatch", args)
+	if err != nil {
+		return false, err
+	}
+	return path == pattern, nil
+}
+
+// 获取匹配函数的两个字符串参数
+func matchArgs(name string, args []interface{}) (string, string, error) {
+	if len(args) != 2 {
+		return "", "", fmt.Errorf("%s: expected 2 arguments, got %d", name, len(args))
+	}
+	value, ok1 := args[0].(string)
+	pattern, ok2 := args[1].(string)
+	if !ok1 || !ok2 {
+		return "", "", fmt.Errorf("%s: arguments must be strings", name)
+	}
+	return value, pattern, nil
+}
+
+// 判断资源访问政策是否匹配请求的路径与方法，与模型中的匹配器一致
+func matchUriPolicy(pathMatch string, p *UriPolicy, path, method string) (bool, error) {
+	ok, err := matchPath(pathMatch, path, p.Path)
+	if err != nil {
+		return false, err
+	}
+	return ok && methodMatch(method, p.Method), nil
+}
+
+// 按路径匹配方式判断请求路径是否匹配政策路径
+func matchPath(pathMatch, path, pattern string) (bool, error) {
+	if pathMatch == "" {
+		pathMatch = PathMatchExact
+	}
+	pathMatchFunc, ok := pathMatchFuncs[pathMatch]
+	if !ok {
+		return false, errors.New(ErrorCasbinPathMatchInvalid)
+	}
+	matched, err := pathMatchFunc(path, pattern)
+	if err != nil {
+		return false, err
+	}
+	return matched.(bool), nil
+}
@@ -0,0 +1,77 @@
+package rbac
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMethodMatch(t *testing.T) {
+	assert.True(t, methodMatch("GET", "GET"))
+	assert.True(t, methodMatch("DELETE", "*"))
+	assert.True(t, methodMatch("POST", "GET|POST"))
+	assert.True(t, methodMatch("POST", "GET | POST"))
+	assert.False(t, methodMatch("PUT", "GET|POST"))
+	assert.False(t, methodMatch("get", "GET"))
+}
+
+func TestRbac_PathMatch(t *testing.T) {
+	var (
+		path   = filepath.Join(t.TempDir(), "policy.csv")
+		policy = "p, role::reader, default, /users/:id, GET|HEAD\n" +
+			"p, role::reader, default, /files/*, *\n" +
+			"p, role::writer, default, /parent/{id}/child/{id}, PUT\n" +
+			"p, role::auditor, default, ^/logs/[0-9]+$, GET\n"
+	)
+
+	assert.NoError(t, os.WriteFile(path, []byte(policy), 0644))
+	newRbac := func(pathMatch string) *Rbac {
+		r, err := New(Settings{
+			TokenSignKey:   []byte("gVoiG1fbXf65osbjfi33MZre"),
+			PolicyFilePath: path,
+			PathMatch:      pathMatch,
+			// 同一政策文件用于全部匹配方式，正则表达式路径在其它匹配方式下无法通过检查
+			PolicyLint: PolicyLintOptions{Disabled: true},
+		})
+		assert.NoError(t, err)
+		return r
+	}
+
+	t.Run("TestRbac_PathMatch_Exact", func(t *testing.T) {
+		r := newRbac("")
+		assert.NoError(t, r.VerifyRequest("/users/:id", "GET", "reader"))
+		assert.Error(t, r.VerifyRequest("/users/1", "GET", "reader"))
+	})
+
+	t.Run("TestRbac_PathMatch_KeyMatch2", func(t *testing.T) {
+		r := newRbac(PathMatchKeyMatch2)
+		assert.NoError(t, r.VerifyRequest("/users/1", "GET", "reader"))
+		assert.NoError(t, r.VerifyRequest("/users/1", "HEAD", "reader"))
+		assert.Error(t, r.VerifyRequest("/users/1", "DELETE", "reader"))
+		assert.Error(t, r.VerifyRequest("/users/1/posts", "GET", "reader"))
+		assert.NoError(t, r.VerifyRequest("/files/a/b.txt", "DELETE", "reader"))
+	})
+
+	t.Run("TestRbac_PathMatch_KeyMatch4", func(t *testing.T) {
+		r := newRbac(PathMatchKeyMatch4)
+		assert.NoError(t, r.VerifyRequest("/parent/1/child/1", "PUT", "writer"))
+		assert.Error(t, r.VerifyRequest("/parent/1/child/2", "PUT", "writer"))
+	})
+
+	t.Run("TestRbac_PathMatch_Regex", func(t *testing.T) {
+		r := newRbac(PathMatchRegex)
+		assert.NoError(t, r.VerifyRequest("/logs/42", "GET", "auditor"))
+		assert.Error(t, r.VerifyRequest("/logs/latest", "GET", "auditor"))
+	})
+
+	t.Run("TestRbac_PathMatch_Invalid", func(t *testing.T) {
+		_, err := New(Settings{
+			TokenSignKey: []byte("gVoiG1fbXf65osbjfi33MZre"),
+			PathMatch:    "glob",
+		})
+		assert.Error(t, err)
+		assert.Equal(t, ErrorCasbinPathMatchInvalid, err.Error())
+	})
+}
@@ -12,6 +12,20 @@ import (
 	"strings"
 )
 
+// 角色名称前缀
+const rolePrefix = "role::"
+
+// 超级管理员
+const rootSubject = "root"
+
+// 获取角色在授权政策中的主体名称，未带前缀的角色名称添加 role:: 前缀
+func roleSubject(role string) string {
+	if role == rootSubject || strings.HasPrefix(role, rolePrefix) {
+		return role
+	}
+	return rolePrefix + role
+}
+
 // 授权政策接口
 type IPolicy interface {
 	FormatLine() string // 格式化行字符串
@@ -21,8 +35,9 @@ type IPolicy interface {
 type UriPolicy struct {
 	Role   string // 用户角色
 	Domain string // 域
-	Path   string // 资源路径
-	Method string // 请求方法
+	Path   string // 资源路径，按Settings.PathMatch匹配，如 /users/:id
+	Method string // 请求方法，支持通配符 * 以及多个方法 GET|POST
+	Effect string // 政策效果，allow(默认)或deny，拒绝优先于允许
 }
 
 // 角色关系政策
@@ -39,8 +54,8 @@ type RolePolicy struct {
 // 	)
 
 // 	strArr = append(strArr, "g")
-// 	strArr = append(strArr, "role::"+r.ParentRole)
-// 	strArr = append(strArr, "root")
+// 	strArr = append(strArr, rolePrefix+r.ParentRole)
+// 	strArr = append(strArr, rootSubject)
 // 	strArr = append(strArr, r.Domain)
 
 // 	return strings.Join(strArr, ", ")
@@ -53,16 +68,25 @@ func (u *UriPolicy) FormatLine() string {
 	)
 
 	strArr = append(strArr, "p")
-	strArr = append(strArr, "role::"+u.Role)
-	strArr = append(strArr, u.Domain)
-	strArr = append(strArr, u.Path)
-	strArr = append(strArr, u.Method)
+	strArr = append(strArr, formatField(rolePrefix+u.Role))
+	strArr = append(strArr, formatField(u.Domain))
+	strArr = append(strArr, formatField(u.Path))
+	strArr = append(strArr, formatField(u.Method))
+	// 未设置效果时保持旧版格式
+	if u.Effect != "" {
+		strArr = append(strArr, formatField(u.Effect))
+	}
 
 	return strings.Join(strArr, ", ")
 }
 
 // 角色关系政策，实现格式化行字符串
 func (r *RolePolicy) FormatLine() string {
+	return r.formatLine(rootSubject)
+}
+
+// 格式化行字符串，没有父级角色时挂在指定的超级管理员下
+func (r *RolePolicy) formatLine(root string) string {
 	var (
 		strArr []string
 	)
@@ -70,12 +94,104 @@ func (r *RolePolicy) FormatLine() string {
 	strArr = append(strArr, "g")
 	// 默认挂超级管理员在root用户下
 	if r.ParentRole == "" {
-		strArr = append(strArr, "root")
+		strArr = append(strArr, formatField(root))
 	} else {
-		strArr = append(strArr, "role::"+r.ParentRole)
+		strArr = append(strArr, formatField(parentSubject(r.ParentRole)))
 	}
-	strArr = append(strArr, "role::"+r.Role)
-	strArr = append(strArr, r.Domain)
+	strArr = append(strArr, formatField(rolePrefix+r.Role))
+	strArr = append(strArr, formatField(r.Domain))
 
 	return strings.Join(strArr, ", ")
 }
+
+// 格式化字段，包含逗号或引号时按CSV规则加引号，如正则表达式 ^/v{1,2}/users$
+func formatField(field string) string {
+	if strings.ContainsAny(field, ",\"\r\n") {
+		return `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+	}
+	return field
+}
+
+// 资源访问政策规则，未设置效果时为 allow
+func (u *UriPolicy) rule() []string {
+	var (
+		effect = u.Effect
+	)
+
+	if effect == "" {
+		effect = EffectAllow
+	}
+	return []string{rolePrefix + u.Role, u.Domain, u.Path, u.Method, effect}
+}
+
+// 判断资源访问政策是否满足过滤条件，为空的字段不参与过滤
+func (u *UriPolicy) match(p *UriPolicy) bool {
+	return matchField(u.Role, p.Role) &&
+		matchField(u.Domain, p.Domain) &&
+		matchField(u.Path, p.Path) &&
+		matchField(u.Method, p.Method) &&
+		matchField(u.Effect, p.Effect)
+}
+
+// 角色关系政策规则，没有父级角色时挂在指定的超级管理员下
+func (r *RolePolicy) rule(root string) []string {
+	var (
+		parent = root
+	)
+
+	if r.ParentRole != "" {
+		parent = parentSubject(r.ParentRole)
+	}
+	return []string{parent, rolePrefix + r.Role, r.Domain}
+}
+
+// 判断角色关系政策是否满足过滤条件，为空的字段不参与过滤
+func (r *RolePolicy) match(p *RolePolicy) bool {
+	return matchField(r.ParentRole, p.ParentRole) &&
+		matchField(r.Role, p.Role) &&
+		matchField(r.Domain, p.Domain)
+}
+
+// 获取父级角色的主体名称
+// 自定义超级管理员后 root 是普通的角色，与政策文件中的写法一致不添加前缀
+func parentSubject(parent string) string {
+	if parent == rootSubject {
+		return rootSubject
+	}
+	return rolePrefix + parent
+}
+
+// 由政策规则获取资源访问政策
+func uriPolicyFromRule(rule []string) UriPolicy {
+	var (
+		p UriPolicy
+	)
+
+	p.Role = strings.TrimPrefix(rule[0], rolePrefix)
+	p.Domain = rule[1]
+	p.Path = rule[2]
+	p.Method = rule[3]
+	if len(rule) > 4 {
+		p.Effect = rule[4]
+	}
+	return p
+}
+
+// 由政策规则获取角色关系政策，父级为超级管理员时视为没有父级角色
+func rolePolicyFromRule(rule []string, root string) RolePolicy {
+	var (
+		p RolePolicy
+	)
+
+	if rule[0] != root {
+		p.ParentRole = strings.TrimPrefix(rule[0], rolePrefix)
+	}
+	p.Role = strings.TrimPrefix(rule[1], rolePrefix)
+	p.Domain = rule[2]
+	return p
+}
+
+// 过滤条件为空或与值相等
+func matchField(filter, value string) bool {
+	return filter == "" || filter == value
+}
@@ -0,0 +1,166 @@
+package rbac
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// 政策行错误，包含出错的行号
+type PolicyLineError struct {
+	Line int    // 行号，从1开始
+	Text string // 行内容
+	Err  string // 错误信息
+}
+
+func (e *PolicyLineError) Error() string {
+	return fmt.Sprintf("policy line %d: %s", e.Line, e.Err)
+}
+
+// 解析政策行，返回 *UriPolicy 或 *RolePolicy
+// 去除 role:: 前缀，父级为 root 时视为没有父级角色；空行与 # 开头的注释行返回nil
+// 自定义了超级管理员时请使用 Casbin.ParsePolicies 或 Casbin.ParsePolicyFile
+func ParsePolicyLine(line string) (IPolicy, error) {
+	return parsePolicyLine(line, rootSubject)
+}
+
+// 解析政策行，父级为指定的超级管理员主体时视为没有父级角色
+func parsePolicyLine(line, root string) (IPolicy, error) {
+	var (
+		fields []string
+		err    error
+	)
+
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil, nil
+	}
+
+	r := csv.NewReader(strings.NewReader(line))
+	r.TrimLeadingSpace = true
+	if fields, err = r.Read(); err != nil {
+		return nil, errors.New(ErrorPolicyLineInvalid)
+	}
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+
+	switch fields[0] {
+	case "p":
+		return parseUriPolicy(fields[1:])
+	case "g":
+		return parseRolePolicy(fields[1:], root)
+	}
+	return nil, errors.New(ErrorPolicyTypeInvalid)
+}
+
+// 解析政策，返回资源访问政策与角色关系政策，出错时返回 *PolicyLineError
+func ParsePolicies(reader io.Reader) ([]UriPolicy, []RolePolicy, error) {
+	return parsePolicies(reader, rootSubject)
+}
+
+// 解析政策文件
+func ParsePolicyFile(path string) ([]UriPolicy, []RolePolicy, error) {
+	return parsePolicyFile(path, rootSubject)
+}
+
+// 使用超级管理员设置解析政策，挂在超级管理员下的角色视为没有父级角色
+func (c *Casbin) ParsePolicies(reader io.Reader) ([]UriPolicy, []RolePolicy, error) {
+	return parsePolicies(reader, c.SuperRole.subject())
+}
+
+// 使用超级管理员设置解析政策文件
+func (c *Casbin) ParsePolicyFile(path string) ([]UriPolicy, []RolePolicy, error) {
+	return parsePolicyFile(path, c.SuperRole.subject())
+}
+
+// 解析政策，父级为指定的超级管理员主体时视为没有父级角色
+func parsePolicies(reader io.Reader, root string) ([]UriPolicy, []RolePolicy, error) {
+	var (
+		scanner = bufio.NewScanner(reader)
+		ups     []UriPolicy
+		rps     []RolePolicy
+		number  int
+	)
+
+	for scanner.Scan() {
+		number++
+		p, err := parsePolicyLine(scanner.Text(), root)
+		if err != nil {
+			return nil, nil, &PolicyLineError{Line: number, Text: scanner.Text(), Err: err.Error()}
+		}
+		switch v := p.(type) {
+		case *UriPolicy:
+			ups = append(ups, *v)
+		case *RolePolicy:
+			rps = append(rps, *v)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return ups, rps, nil
+}
+
+// 解析政策文件，父级为指定的超级管理员主体时视为没有父级角色
+func parsePolicyFile(path, root string) ([]UriPolicy, []RolePolicy, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	return parsePolicies(file, root)
+}
+
+// 解析资源访问政策字段：角色, 域, 路径, 方法[, 效果]
+func parseUriPolicy(fields []string) (*UriPolicy, error) {
+	var (
+		p = &UriPolicy{}
+	)
+
+	if len(fields) != 4 && len(fields) != 5 {
+		return nil, errors.New(ErrorPolicyFieldsInvalid)
+	}
+	p.Role = strings.TrimPrefix(fields[0], rolePrefix)
+	p.Domain = fields[1]
+	p.Path = fields[2]
+	p.Method = fields[3]
+	if len(fields) == 5 {
+		p.Effect = fields[4]
+		if p.Effect != EffectAllow && p.Effect != EffectDeny {
+			return nil, errors.New(ErrorPolicyEffectInvalid)
+		}
+	}
+	if p.Role == "" || p.Domain == "" || p.Path == "" || p.Method == "" {
+		return nil, errors.New(ErrorPolicyFieldsInvalid)
+	}
+
+	return p, nil
+}
+
+// 解析角色关系政策字段：父级角色, 角色, 域
+func parseRolePolicy(fields []string, root string) (*RolePolicy, error) {
+	var (
+		p = &RolePolicy{}
+	)
+
+	if len(fields) != 3 {
+		return nil, errors.New(ErrorPolicyFieldsInvalid)
+	}
+	if fields[0] != root {
+		p.ParentRole = strings.TrimPrefix(fields[0], rolePrefix)
+	}
+	p.Role = strings.TrimPrefix(fields[1], rolePrefix)
+	p.Domain = fields[2]
+	if fields[0] == "" || p.Role == "" || p.Domain == "" {
+		return nil, errors.New(ErrorPolicyFieldsInvalid)
+	}
+
+	return p, nil
+}
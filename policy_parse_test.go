@@ -0,0 +1,131 @@
+package rbac
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePolicyLine(t *testing.T) {
+	t.Run("TestParsePolicyLine_UriPolicy", func(t *testing.T) {
+		p, err := ParsePolicyLine("p, role::admin,manager ,  /user , GET|POST, deny")
+		assert.NoError(t, err)
+		assert.Equal(t, &UriPolicy{Role: "admin", Domain: "manager", Path: "/user", Method: "GET|POST", Effect: EffectDeny}, p)
+	})
+
+	t.Run("TestParsePolicyLine_RolePolicy", func(t *testing.T) {
+		p, err := ParsePolicyLine("g, root, role::admin1, manager")
+		assert.NoError(t, err)
+		assert.Equal(t, &RolePolicy{Role: "admin1", Domain: "manager"}, p)
+
+		p, err = ParsePolicyLine("g, role::admin1, role::admin2, manager")
+		assert.NoError(t, err)
+		assert.Equal(t, &RolePolicy{ParentRole: "admin1", Role: "admin2", Domain: "manager"}, p)
+	})
+
+	t.Run("TestParsePolicyLine_Quoted", func(t *testing.T) {
+		up := &UriPolicy{Role: "reader", Domain: "default", Path: `^/v{1,2}/"users"$`, Method: "GET"}
+		p, err := ParsePolicyLine(up.FormatLine())
+		assert.NoError(t, err)
+		assert.Equal(t, up, p)
+	})
+
+	t.Run("TestParsePolicyLine_Skip", func(t *testing.T) {
+		p, err := ParsePolicyLine("  # comment")
+		assert.NoError(t, err)
+		assert.Nil(t, p)
+	})
+
+	t.Run("TestParsePolicyLine_Invalid", func(t *testing.T) {
+		for line, msg := range map[string]string{
+			"x, role::admin, manager":                 ErrorPolicyTypeInvalid,
+			"p, role::admin, manager, /user":          ErrorPolicyFieldsInvalid,
+			"g, root, role::admin":                    ErrorPolicyFieldsInvalid,
+			"p, role::admin, manager, /user, GET, ok": ErrorPolicyEffectInvalid,
+			`p, role::admin, "manager, /user, GET`:    ErrorPolicyLineInvalid,
+		} {
+			_, err := ParsePolicyLine(line)
+			assert.Error(t, err, line)
+			assert.Equal(t, msg, err.Error(), line)
+		}
+	})
+}
+
+func TestParsePolicies(t *testing.T) {
+	t.Run("TestParsePolicies_RoundTrip", func(t *testing.T) {
+		ups, rps, err := ParsePolicyFile("examples/policy.csv")
+		assert.NoError(t, err)
+		assert.Len(t, ups, 10)
+		assert.Len(t, rps, 6)
+
+		var lines []string
+		for _, p := range ups {
+			lines = append(lines, p.FormatLine())
+		}
+		for _, p := range rps {
+			lines = append(lines, p.FormatLine())
+		}
+		ups2, rps2, err := ParsePolicies(strings.NewReader(strings.Join(lines, "\n")))
+		assert.NoError(t, err)
+		assert.Equal(t, ups, ups2)
+		assert.Equal(t, rps, rps2)
+	})
+
+	t.Run("TestParsePolicies_LineNumber", func(t *testing.T) {
+		var lineErr *PolicyLineError
+
+		_, _, err := ParsePolicies(strings.NewReader("# policies\n\np, role::admin, manager, /user, GET\ng, root, role::admin\n"))
+		assert.Error(t, err)
+		assert.True(t, errors.As(err, &lineErr))
+		assert.Equal(t, 4, lineErr.Line)
+		assert.Equal(t, "policy line 4: "+ErrorPolicyFieldsInvalid, err.Error())
+	})
+}
+
+func TestCasbin_ParsePolicyFile_SuperRole(t *testing.T) {
+	var (
+		path  = filepath.Join(t.TempDir(), "policy.csv")
+		lines = "p, role::admin, default, /users, GET\n" +
+			"p, role::root, default, /users, POST\n" +
+			"g, role::sys, role::admin, default\n" +
+			"g, root, role::admin, default\n"
+		c = NewCasbin(path)
+	)
+
+	assert.NoError(t, os.WriteFile(path, []byte(lines), 0644))
+	c.SuperRole = SuperRole{Roles: []string{"sys"}}
+
+	ups, rps, err := c.ParsePolicyFile(path)
+	assert.NoError(t, err)
+	// 挂在超级管理员下的角色没有父级角色，root 是普通的角色
+	assert.Equal(t, []RolePolicy{
+		{Role: "admin", Domain: "default"},
+		{ParentRole: "root", Role: "admin", Domain: "default"},
+	}, rps)
+
+	// 与加载的政策一致
+	listed, err := c.ListRolePolicies(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, rps, listed)
+	d, err := c.DiffPolicies(ups, rps)
+	assert.NoError(t, err)
+	assert.True(t, d.Empty())
+	issues, err := LintPolicyFile(path, c.lintOptions())
+	assert.NoError(t, err)
+	assert.Empty(t, issues)
+
+	// 保存后政策文件不变
+	assert.NoError(t, c.SaveAllPolicyCsv(ups, rps))
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, lines, string(data))
+
+	// 未指定超级管理员时 sys 是父级角色
+	_, rps, err = ParsePolicyFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "sys", rps[0].ParentRole)
+}
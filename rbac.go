@@ -9,6 +9,7 @@
 package rbac
 
 import (
+	"crypto"
 	"errors"
 	"time"
 )
@@ -21,12 +22,30 @@ type Rbac struct {
 
 // 设置项
 type Settings struct {
-	DefaultDomain          string
-	PolicyFilePath         string        // 可选项，授权政策文件路径；当使用默认的adapter时为必填
-	TokenSignKey           []byte        // 必填项，Jwt加密字符串，使用随机的字符串即可
-	TokenIssuer            string        // 选填项，Jwt的签发者，如lgcgo.com
-	AccessTokenExpireTime  time.Duration // 可选项，access_token过期时间，默认24小时
-	RefreshTokenExpireTime time.Duration // 可选项，refresh_token过期时间，默认是access_token过期时间的3倍数
+	DefaultDomain          string            // 可选项，默认域，签发授权与验证请求未指定域时使用，默认为default
+	PolicyFilePath         string            // 可选项，授权政策文件路径；当使用默认的adapter时为必填
+	PolicyBackups          int               // 可选项，保存政策文件时保留的备份数量，0为不备份
+	Watcher                Watcher           // 可选项，政策变更通知，多个节点共享授权政策时同步变更
+	PathMatch              string            // 可选项，路径匹配方式，exact(默认)、keyMatch2、keyMatch4、regex
+	SuperRole              SuperRole         // 可选项，超级管理员设置，默认 root 在所有域直接放行
+	PolicyLint             PolicyLintOptions // 可选项，保存与重新加载授权政策前的检查，存在错误时拒绝保存与加载
+	TokenSignKey           []byte            // 可选项，Jwt加密字符串(HS256)，使用随机的字符串即可；未设置非对称密钥时为必填
+	TokenSigner            crypto.Signer     // 可选项，非对称签名器(RS256/ES256/EdDSA)，优先于TokenPrivateKeyFile
+	TokenPrivateKeyFile    string            // 可选项，非对称签名私钥PEM文件路径
+	TokenPublicKey         crypto.PublicKey  // 可选项，非对称验证公钥；只设置公钥时为只验证实例，不能签发授权
+	TokenPublicKeyFile     string            // 可选项，非对称验证公钥PEM文件路径
+	TokenKeyID             string            // 可选项，签名密钥的kid，密钥轮换时用于区分新旧密钥；非对称密钥默认使用JWK指纹
+	JWKSFile               string            // 可选项，JWKS文件路径；设置后为只验证实例，使用其中的公钥验证Token
+	JWKSFetcher            JWKSFetcher       // 可选项，JWKS获取器，例如从签发方的 /.well-known/jwks.json 获取；优先于JWKSFile
+	JWKSRefreshInterval    time.Duration     // 可选项，遇到未知kid时重新获取JWKS的最小间隔，默认1分钟
+	TokenIssuer            string            // 选填项，Jwt的签发者，如lgcgo.com；设置后验证Token的iss
+	TokenAudience          []string          // 可选项，Jwt的授众；签发时的默认授众，设置后验证Token的aud
+	TokenAudienceMatch     string            // 可选项，授众匹配方式，any=包含任一授众(默认)，all=包含全部授众
+	TokenLeeway            time.Duration     // 可选项，验证Token时允许的时钟偏差
+	TokenMaxAge            time.Duration     // 可选项，Token签发后的最长使用时间，0为不限制
+	AccessTokenExpireTime  time.Duration     // 可选项，access_token过期时间，默认24小时
+	RefreshTokenExpireTime time.Duration     // 可选项，refresh_token过期时间，默认是access_token过期时间的3倍数
+	RevocationStore        RevocationStore   // 可选项，Token吊销存储，默认使用内存存储
 }
 
 // 授权返回结构
@@ -37,20 +56,31 @@ type Token struct {
 	RefreshToken string `json:"refreshToken"`
 }
 
-var insRabc = &Rbac{}
-
+// 实例化Rbac，每次调用返回独立的实例，多个实例可以使用不同的密钥与授权政策
 func New(sets Settings) (*Rbac, error) {
 	var (
+		r        = &Rbac{}
 		duration time.Duration
+		key      *SigningKey
+		err      error
 	)
 
 	// 设置默认Domain
 	if sets.DefaultDomain == "" {
 		sets.DefaultDomain = "default"
 	}
-	// 验证加密密钥
-	if sets.TokenSignKey == nil || len(sets.TokenSignKey) == 0 {
-		return nil, errors.New(ErrorTokenSignKeyInvalid)
+	// JWKS验证模式不需要本地密钥
+	if sets.JWKSFetcher == nil && sets.JWKSFile != "" {
+		sets.JWKSFetcher = NewFileJWKSFetcher(sets.JWKSFile)
+	}
+	// 获取签名密钥
+	if sets.JWKSFetcher == nil {
+		if key, err = newSigningKey(sets); err != nil {
+			return nil, err
+		}
+		if key.Kid, err = signingKeyId(key, sets.TokenKeyID); err != nil {
+			return nil, err
+		}
 	}
 	// 设置access_token默认过期时间
 	if sets.AccessTokenExpireTime == 0 {
@@ -65,16 +95,148 @@ func New(sets Settings) (*Rbac, error) {
 	if sets.AccessTokenExpireTime >= sets.RefreshTokenExpireTime {
 		return nil, errors.New(ErrorRefreshTokenExpireTimeInvalid)
 	}
+	// 设置默认吊销存储
+	if sets.RevocationStore == nil {
+		sets.RevocationStore = NewMemoryRevocationStore()
+	}
+
+	r.settings = sets
+	r.Jwt = NewJwtWithKey(key, sets.TokenIssuer)
+	r.Jwt.SetVerifyOptions(VerifyOptions{
+		Issuer:        sets.TokenIssuer,
+		Audience:      sets.TokenAudience,
+		AudienceMatch: sets.TokenAudienceMatch,
+		Leeway:        sets.TokenLeeway,
+		MaxAge:        sets.TokenMaxAge,
+	})
+	if sets.JWKSRefreshInterval > 0 {
+		r.Jwt.SetJWKSRefreshInterval(sets.JWKSRefreshInterval)
+	}
+	if sets.JWKSFetcher != nil {
+		if err = r.Jwt.SetJWKSFetcher(sets.JWKSFetcher); err != nil {
+			return nil, err
+		}
+	}
+	r.Casbin = NewCasbin(sets.PolicyFilePath)
+	r.Casbin.PolicyBackups = sets.PolicyBackups
+	if err = r.Casbin.SetPathMatch(sets.PathMatch); err != nil {
+		return nil, err
+	}
+	r.Casbin.SuperRole = sets.SuperRole
+	r.Casbin.PolicyLint = sets.PolicyLint
+	if sets.Watcher != nil {
+		if err = r.Casbin.SetWatcher(sets.Watcher); err != nil {
+			return nil, err
+		}
+	}
+	// 创建执行器并加载授权政策，验证请求时复用
+	if sets.PolicyFilePath != "" {
+		if err = r.Casbin.Init(); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// 根据设置项获取签名密钥，优先级：签名器 > 私钥文件 > 公钥 > 公钥文件 > HMAC密钥
+func newSigningKey(sets Settings) (*SigningKey, error) {
+	switch {
+	case sets.TokenSigner != nil:
+		return NewSignerKey(sets.TokenSigner)
+	case sets.TokenPrivateKeyFile != "":
+		return LoadPrivateKeyFile(sets.TokenPrivateKeyFile)
+	case sets.TokenPublicKey != nil:
+		return NewVerifyKey(sets.TokenPublicKey)
+	case sets.TokenPublicKeyFile != "":
+		return LoadPublicKeyFile(sets.TokenPublicKeyFile)
+	case len(sets.TokenSignKey) > 0:
+		return NewHMACKey(sets.TokenSignKey), nil
+	}
+
+	return nil, errors.New(ErrorTokenSignKeyInvalid)
+}
+
+// 获取签名密钥的kid，未指定时非对称密钥使用JWK指纹，HMAC密钥不设置kid
+func signingKeyId(key *SigningKey, kid string) (string, error) {
+	if _, ok := key.VerifyKey.([]byte); ok || kid != "" {
+		return kid, nil
+	}
+	jwk, err := NewJWK(key)
+	if err != nil {
+		return "", err
+	}
+	return jwk.Thumbprint()
+}
 
-	insRabc.settings = sets
-	insRabc.Jwt = NewJwt(sets.TokenSignKey, sets.TokenIssuer)
-	insRabc.Casbin = NewCasbin(sets.PolicyFilePath)
+// 重新获取JWKS，JWKS验证模式下使用
+func (r *Rbac) RefreshJWKS() error {
+	return r.Jwt.RefreshJWKS()
+}
+
+// 轮换签名密钥，新签发的Token使用新密钥；原密钥在refresh_token有效期内继续用于验证，用户无需重新登录
+func (r *Rbac) RotateSigningKey(key *SigningKey) error {
+	return r.Jwt.KeyRing().Rotate(key, r.settings.RefreshTokenExpireTime)
+}
 
-	return insRabc, nil
+// 授权参数
+type Grant struct {
+	Subject     string                 // 主题，一般为用户的唯一标识
+	Role        string                 // 角色，多角色时可为空，默认取Roles中的第一个
+	Roles       []string               // 可选项，角色列表，用户同时拥有多个角色时使用
+	DomainRoles map[string][]string    // 可选项，域角色，只在指定域中有效的角色
+	Domain      string                 // 可选项，签发域(租户)，验证请求时使用，默认为Settings.DefaultDomain
+	Audience    []string               // 可选项，授众，默认为Settings.TokenAudience
+	Extra       map[string]interface{} // 可选项，自定义声明，如租户ID、显示名称、权限范围等；刷新授权时沿用
 }
 
 // 签发授权（oauth2密码模式）
 func (r *Rbac) Authorization(subject, role string) (*Token, error) {
+	return r.IssueAuthorization(&Grant{
+		Subject: subject,
+		Role:    role,
+	})
+}
+
+// 按授权参数签发授权，支持自定义声明
+func (r *Rbac) IssueAuthorization(grant *Grant) (*Token, error) {
+	var (
+		role     = grant.Role
+		domain   = grant.Domain
+		audience = grant.Audience
+		family   string
+		err      error
+	)
+
+	// 每次授权开启新的授权家族
+	if family, err = newTokenId(); err != nil {
+		return nil, err
+	}
+	if domain == "" {
+		domain = r.settings.DefaultDomain
+	}
+	if len(audience) == 0 {
+		audience = r.settings.TokenAudience
+	}
+	// 兼容单角色的isr声明
+	if role == "" && len(grant.Roles) > 0 {
+		role = grant.Roles[0]
+	}
+
+	return r.authorize(&IssueClaims{
+		Subject:     grant.Subject,
+		Role:        role,
+		Roles:       grant.Roles,
+		DomainRoles: grant.DomainRoles,
+		Domain:      domain,
+		Audience:    audience,
+		Family:      family,
+		Extra:       grant.Extra,
+	})
+}
+
+// 签发 accessToken 与 refreshToken
+func (r *Rbac) authorize(iClaims *IssueClaims) (*Token, error) {
 	var (
 		sets         = r.settings
 		currentTime  = time.Now()
@@ -85,11 +247,6 @@ func (r *Rbac) Authorization(subject, role string) (*Token, error) {
 		expiresIn    float64
 	)
 
-	// 实例化签名
-	iClaims := &IssueClaims{
-		Subject: subject,
-		Role:    role,
-	}
 	// 制作 accessToken
 	iClaims.Type = "grant"
 	if accessToken, err = r.Jwt.IssueToken(iClaims, sets.AccessTokenExpireTime); err != nil {
@@ -115,9 +272,11 @@ func (r *Rbac) Authorization(subject, role string) (*Token, error) {
 }
 
 // 刷新授权
+// refreshToken只能使用一次，刷新后签发同一授权家族的新refreshToken；
+// 已使用的refreshToken再次出现时，视为泄露，吊销整个授权家族
 func (r *Rbac) RefreshAuthorization(ticket string) (*Token, error) {
 	var (
-		claims map[string]interface{}
+		claims *Claims
 		err    error
 	)
 
@@ -126,17 +285,34 @@ func (r *Rbac) RefreshAuthorization(ticket string) (*Token, error) {
 		return nil, err
 	}
 	// 校验签发类型
-	if claims["ist"] != "renew" {
+	if claims.GetIssueType() != "renew" {
 		return nil, errors.New(ErrorTokenIssueTypeInvalid)
 	}
+	// 校验是否已吊销
+	if err = r.checkRevoked(claims); err != nil {
+		return nil, err
+	}
+	// 标记为已使用，检测重复使用
+	if err = r.useRefreshToken(claims); err != nil {
+		return nil, err
+	}
 
-	return r.Authorization(claims["sub"].(string), claims["isr"].(string))
+	return r.authorize(&IssueClaims{
+		Subject:     claims.GetSubject(),
+		Role:        claims.GetRole(),
+		Roles:       claims.IssueRoles,
+		DomainRoles: claims.DomainRoles,
+		Domain:      claims.GetDomain(),
+		Audience:    claims.GetAudience(),
+		Family:      claims.GetFamily(),
+		Extra:       claims.Extra,
+	})
 }
 
 // 验证Token
-func (r *Rbac) VerifyToken(ticket string) (map[string]interface{}, error) {
+func (r *Rbac) VerifyToken(ticket string) (*Claims, error) {
 	var (
-		claims map[string]interface{}
+		claims *Claims
 		err    error
 	)
 
@@ -145,28 +321,188 @@ func (r *Rbac) VerifyToken(ticket string) (map[string]interface{}, error) {
 		return nil, err
 	}
 	// 非法的签发类型
-	if claims["ist"] != "grant" {
+	if claims.GetIssueType() != "grant" {
 		return nil, errors.New(ErrorTokenIssueTypeInvalid)
 	}
+	// 校验是否已吊销
+	if err = r.checkRevoked(claims); err != nil {
+		return nil, err
+	}
 
 	return claims, nil
 }
 
-// 验证角色请求
-func (r *Rbac) VerifyRequest(path, method, role string) error {
+// 吊销Token，access_token和refresh_token均可吊销
+func (r *Rbac) RevokeToken(ticket string) error {
 	var (
-		err error
+		claims *Claims
+		err    error
 	)
 
-	// 初始化Casbin组件
-	if err = r.Casbin.Init(); err != nil {
+	// 解析Token，过期的Token无需吊销
+	if claims, err = r.Jwt.ParseToken(ticket); err != nil {
 		return err
 	}
+	if claims.GetID() == "" {
+		return errors.New(ErrorJwtClaimsInvaild)
+	}
+	_, err = r.settings.RevocationStore.Revoke(revokeTokenPrefix+claims.GetID(), claims.GetExpiresAt())
 
-	return r.Casbin.VerifyUriPolicy(&UriPolicy{
-		Role:   role,
-		Domain: r.Casbin.Domain,
+	return err
+}
+
+// 吊销主题(用户)在此之前签发的全部Token，常用于修改密码、强制下线
+func (r *Rbac) RevokeSubject(subject string) error {
+	var (
+		// 吊销记录保留到此前签发的Token全部过期
+		expiresAt = time.Now().Add(r.settings.RefreshTokenExpireTime)
+		err       error
+	)
+
+	_, err = r.settings.RevocationStore.Revoke(revokeSubjectPrefix+subject, expiresAt)
+
+	return err
+}
+
+// 标记refreshToken已使用，重复使用时吊销整个授权家族
+func (r *Rbac) useRefreshToken(claims *Claims) error {
+	var (
+		store   = r.settings.RevocationStore
+		existed bool
+		err     error
+	)
+
+	// 升级前签发的Token没有jti，无法追踪
+	if claims.GetID() == "" {
+		return nil
+	}
+	if existed, err = store.Revoke(refreshUsedPrefix+claims.GetID(), claims.GetExpiresAt()); err != nil {
+		return err
+	}
+	if !existed {
+		return nil
+	}
+	if claims.GetFamily() != "" {
+		// 家族中最后签发的Token不晚于refresh_token有效期
+		if _, err = store.Revoke(revokeFamilyPrefix+claims.GetFamily(), time.Now().Add(r.settings.RefreshTokenExpireTime)); err != nil {
+			return err
+		}
+	}
+
+	return errors.New(ErrorTokenRefreshReused)
+}
+
+// 校验Token是否已被吊销
+func (r *Rbac) checkRevoked(claims *Claims) error {
+	var (
+		store     = r.settings.RevocationStore
+		revokedAt time.Time
+		revoked   bool
+		err       error
+	)
+
+	// 升级前签发的Token没有jti
+	if claims.GetID() != "" {
+		if _, revoked, err = store.Lookup(revokeTokenPrefix + claims.GetID()); err != nil {
+			return err
+		}
+		if revoked {
+			return errors.New(ErrorTokenRevoked)
+		}
+	}
+	if claims.GetFamily() != "" {
+		if _, revoked, err = store.Lookup(revokeFamilyPrefix + claims.GetFamily()); err != nil {
+			return err
+		}
+		if revoked {
+			return errors.New(ErrorTokenRevoked)
+		}
+	}
+	// 签发时间不晚于吊销时间的Token视为已吊销，使用精确到纳秒的签发时间，吊销后立即签发的Token仍然有效
+	if claims.GetSubject() != "" {
+		if revokedAt, revoked, err = store.Lookup(revokeSubjectPrefix + claims.GetSubject()); err != nil {
+			return err
+		}
+		if revoked && !claims.issuedAtPrecise().After(revokedAt) {
+			return errors.New(ErrorTokenRevoked)
+		}
+	}
+
+	return nil
+}
+
+// 重新加载授权政策，授权政策变更后调用
+func (r *Rbac) Reload() error {
+	return r.Casbin.Reload()
+}
+
+// 验证角色请求，使用默认域
+func (r *Rbac) VerifyRequest(path, method, role string) error {
+	return r.VerifyDomainRequest("", path, method, role)
+}
+
+// 验证角色在指定域中的请求，域为空时使用默认域
+func (r *Rbac) VerifyDomainRequest(domain, path, method, role string) error {
+	return r.verifyRoles(r.domain(domain), path, method, []string{role})
+}
+
+// 验证多角色请求，使用默认域，任一角色满足授权政策即可
+func (r *Rbac) VerifyRolesRequest(path, method string, roles []string) error {
+	return r.verifyRoles(r.domain(""), path, method, roles)
+}
+
+// 验证Token声明的请求，使用Token的签发域，
+// Token中在该域有效的任一角色满足授权政策即可
+func (r *Rbac) VerifyClaimsRequest(claims *Claims, path, method string) error {
+	var (
+		domain = r.domain(claims.GetDomain())
+	)
+
+	return r.verifyRoles(domain, path, method, claims.GetDomainRoles(domain))
+}
+
+// 说明角色在指定域中的请求为何放行或拒绝，域为空时使用默认域
+func (r *Rbac) ExplainRequest(domain, path, method, role string) (*Decision, error) {
+	return r.Casbin.ExplainUriPolicy(&UriPolicy{
+		Role:   roleSubject(role),
+		Domain: r.domain(domain),
 		Path:   path,
 		Method: method,
 	})
 }
+
+// 获取可以访问路径与方法的全部角色及授予访问的政策，域为空时使用默认域
+func (r *Rbac) ListAccessRoles(domain, path, method string) ([]RoleAccess, error) {
+	return r.Casbin.ListAccessRoles(r.domain(domain), path, method)
+}
+
+// 获取验证请求的域
+// 使用请求指定的域或Token的签发域，没有时为默认域；不读取共享的状态
+func (r *Rbac) domain(domain string) string {
+	if domain != "" {
+		return domain
+	}
+	return r.settings.DefaultDomain
+}
+
+// 验证多角色在指定域中的请求
+func (r *Rbac) verifyRoles(domain, path, method string, roles []string) error {
+	var (
+		err error
+	)
+
+	for _, role := range roles {
+		err = r.Casbin.VerifyUriPolicy(&UriPolicy{
+			Role:   roleSubject(role),
+			Domain: domain,
+			Path:   path,
+			Method: method,
+		})
+		// 非拒绝的错误直接返回
+		if err == nil || err.Error() != ErrorCasbinEnforceInvaild {
+			return err
+		}
+	}
+
+	return errors.New(ErrorCasbinEnforceInvaild)
+}
@@ -1,6 +1,11 @@
 package rbac
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -39,6 +44,52 @@ func TestNew(t *testing.T) {
 	})
 }
 
+func TestNew_Independent(t *testing.T) {
+	var (
+		dir     = t.TempDir()
+		apiPath = filepath.Join(dir, "api.csv")
+		cmsPath = filepath.Join(dir, "cms.csv")
+		api     *Rbac
+		cms     *Rbac
+		token   *Token
+		err     error
+	)
+
+	t.Parallel()
+	assert.NoError(t, os.WriteFile(apiPath, []byte("p, role::reader, default, /user, GET\n"), 0644))
+	assert.NoError(t, os.WriteFile(cmsPath, []byte("p, role::reader, default, /article, GET\n"), 0644))
+
+	api, err = New(Settings{
+		TokenSignKey:   []byte("gVoiG1fbXf65osbjfi33MZre"),
+		TokenIssuer:    "api.lgcgo.com",
+		PolicyFilePath: apiPath,
+	})
+	assert.NoError(t, err)
+	cms, err = New(Settings{
+		TokenSignKey:   []byte("QiY9OoNdGOSezTdO0XbSKLrh"),
+		TokenIssuer:    "cms.lgcgo.com",
+		PolicyFilePath: cmsPath,
+	})
+	assert.NoError(t, err)
+
+	// 后创建的实例不影响先创建的实例
+	assert.NotSame(t, api.Jwt, cms.Jwt)
+	assert.NotSame(t, api.Casbin, cms.Casbin)
+	assert.Equal(t, apiPath, api.Casbin.PolicyFilePath)
+
+	token, err = api.Authorization("uid001", "reader")
+	assert.NoError(t, err)
+	_, err = api.VerifyToken(token.AccessToken)
+	assert.NoError(t, err)
+	_, err = cms.VerifyToken(token.AccessToken)
+	assert.Error(t, err)
+
+	assert.NoError(t, api.VerifyRequest("/user", "GET", "reader"))
+	assert.Error(t, api.VerifyRequest("/article", "GET", "reader"))
+	assert.NoError(t, cms.VerifyRequest("/article", "GET", "reader"))
+	assert.Error(t, cms.VerifyRequest("/user", "GET", "reader"))
+}
+
 func TestRefreshAuthorization(t *testing.T) {
 	t.Run("TestRefreshAuthorization_ErrorTokenIssueTypeFail", func(t *testing.T) {
 		var (
@@ -62,3 +113,292 @@ func TestRefreshAuthorization(t *testing.T) {
 		assert.Equal(t, ErrorTokenIssueTypeInvalid, err.Error())
 	})
 }
+
+func TestRbac_IssueAuthorization(t *testing.T) {
+	var (
+		sets = Settings{
+			TokenSignKey: []byte("gVoiG1fbXf65osbjfi33MZre"),
+			TokenIssuer:  "lgcgo.com",
+		}
+		grant = &Grant{
+			Subject: "uid001",
+			Role:    "editor",
+			Extra: map[string]interface{}{
+				"tid":    "tenant01",
+				"name":   "Jimmy",
+				"scopes": []string{"article:read", "article:write"},
+			},
+		}
+		r      *Rbac
+		token  *Token
+		claims *Claims
+		err    error
+	)
+
+	r, _ = New(sets)
+	token, err = r.IssueAuthorization(grant)
+	assert.NoError(t, err)
+
+	t.Run("TestRbac_IssueAuthorization_ExtraClaims", func(t *testing.T) {
+		claims, err = r.VerifyToken(token.AccessToken)
+		assert.NoError(t, err)
+		tid, ok := claims.GetExtra("tid")
+		assert.True(t, ok)
+		assert.Equal(t, "tenant01", tid)
+	})
+
+	t.Run("TestRbac_IssueAuthorization_DecodeStruct", func(t *testing.T) {
+		var out struct {
+			Claims
+			TenantId string   `json:"tid"`
+			Name     string   `json:"name"`
+			Scopes   []string `json:"scopes"`
+		}
+
+		assert.NoError(t, claims.Decode(&out))
+		assert.Equal(t, "uid001", out.GetSubject())
+		assert.Equal(t, "tenant01", out.TenantId)
+		assert.Equal(t, []string{"article:read", "article:write"}, out.Scopes)
+	})
+
+	t.Run("TestRbac_IssueAuthorization_RefreshCarriesExtra", func(t *testing.T) {
+		renewed, err := r.RefreshAuthorization(token.RefreshToken)
+		assert.NoError(t, err)
+		claims, err := r.VerifyToken(renewed.AccessToken)
+		assert.NoError(t, err)
+		name, _ := claims.GetExtra("name")
+		assert.Equal(t, "Jimmy", name)
+	})
+
+	t.Run("TestRbac_IssueAuthorization_ReservedClaim", func(t *testing.T) {
+		_, err := r.IssueAuthorization(&Grant{
+			Subject: "uid001",
+			Role:    "editor",
+			Extra:   map[string]interface{}{"isr": "root"},
+		})
+		assert.Error(t, err)
+		assert.Equal(t, ErrorClaimsReserved, err.Error())
+	})
+}
+
+func TestRbac_VerifyRolesRequest(t *testing.T) {
+	var (
+		path = filepath.Join(t.TempDir(), "policy.csv")
+		sets = Settings{
+			TokenSignKey:   []byte("gVoiG1fbXf65osbjfi33MZre"),
+			TokenIssuer:    "lgcgo.com",
+			PolicyFilePath: path,
+		}
+		policy = "p, role::reader, default, /article, GET\n" +
+			"p, role::writer, default, /article, POST\n" +
+			"p, role::auditor, audit, /logs, GET\n"
+		r      *Rbac
+		token  *Token
+		claims *Claims
+		err    error
+	)
+
+	assert.NoError(t, os.WriteFile(path, []byte(policy), 0644))
+	r, _ = New(sets)
+
+	t.Run("TestRbac_VerifyRolesRequest_AnyRole", func(t *testing.T) {
+		assert.NoError(t, r.VerifyRolesRequest("/article", "POST", []string{"reader", "writer"}))
+		err = r.VerifyRolesRequest("/article", "DELETE", []string{"reader", "writer"})
+		assert.Error(t, err)
+		assert.Equal(t, ErrorCasbinEnforceInvaild, err.Error())
+	})
+
+	t.Run("TestRbac_VerifyClaimsRequest", func(t *testing.T) {
+		token, err = r.IssueAuthorization(&Grant{
+			Subject:     "uid001",
+			Roles:       []string{"reader", "writer"},
+			DomainRoles: map[string][]string{"audit": {"auditor"}},
+		})
+		assert.NoError(t, err)
+		claims, err = r.VerifyToken(token.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, "reader", claims.GetRole())
+		assert.Equal(t, []string{"reader", "writer"}, claims.GetRoles())
+
+		assert.NoError(t, r.VerifyClaimsRequest(claims, "/article", "POST"))
+		// 域角色只在所属域中有效
+		assert.Error(t, r.VerifyClaimsRequest(claims, "/logs", "GET"))
+		assert.NoError(t, r.VerifyDomainRequest("audit", "/logs", "GET", "auditor"))
+	})
+
+	t.Run("TestRbac_VerifyClaimsRequest_RefreshCarriesRoles", func(t *testing.T) {
+		renewed, err := r.RefreshAuthorization(token.RefreshToken)
+		assert.NoError(t, err)
+		claims, err := r.VerifyToken(renewed.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"reader", "writer"}, claims.GetRoles())
+		assert.Equal(t, []string{"reader", "writer", "auditor"}, claims.GetDomainRoles("audit"))
+	})
+}
+
+func TestRbac_VerifyDomainRequest(t *testing.T) {
+	var (
+		sets = Settings{
+			TokenSignKey:   []byte("gVoiG1fbXf65osbjfi33MZre"),
+			TokenIssuer:    "lgcgo.com",
+			PolicyFilePath: "examples/policy.csv",
+		}
+		r      *Rbac
+		token  *Token
+		claims *Claims
+		err    error
+	)
+
+	r, _ = New(sets)
+
+	t.Run("TestRbac_VerifyDomainRequest_DefaultDomain", func(t *testing.T) {
+		token, _ = r.Authorization("uid001", "userRole1")
+		claims, err = r.VerifyToken(token.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, "default", claims.GetDomain())
+		assert.NoError(t, r.VerifyClaimsRequest(claims, "/user", "GET"))
+	})
+
+	t.Run("TestRbac_VerifyDomainRequest_SharedDomainIgnored", func(t *testing.T) {
+		// 已弃用的共享域不影响验证请求
+		r.Casbin.SetDomain("www")
+		defer r.Casbin.SetDomain("")
+		assert.NoError(t, r.VerifyRequest("/user", "GET", "userRole1"))
+		assert.Error(t, r.VerifyRequest("/article", "GET", "userGroup1"))
+	})
+
+	t.Run("TestRbac_VerifyDomainRequest_TokenDomain", func(t *testing.T) {
+		token, _ = r.IssueAuthorization(&Grant{Subject: "uid001", Role: "userGroup1", Domain: "www"})
+		claims, err = r.VerifyToken(token.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, "www", claims.GetDomain())
+		assert.NoError(t, r.VerifyClaimsRequest(claims, "/article", "GET"))
+		// 相同角色在其它域中无权限
+		assert.Error(t, r.VerifyDomainRequest("manager", "/article", "GET", "userGroup1"))
+	})
+
+	t.Run("TestRbac_VerifyDomainRequest_RefreshCarriesDomain", func(t *testing.T) {
+		renewed, err := r.RefreshAuthorization(token.RefreshToken)
+		assert.NoError(t, err)
+		claims, err := r.VerifyToken(renewed.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, "www", claims.GetDomain())
+	})
+}
+
+func TestRbac_Reload(t *testing.T) {
+	var (
+		path = filepath.Join(t.TempDir(), "policy.csv")
+		sets = Settings{
+			TokenSignKey:   []byte("gVoiG1fbXf65osbjfi33MZre"),
+			TokenIssuer:    "lgcgo.com",
+			PolicyFilePath: path,
+		}
+		r   *Rbac
+		err error
+	)
+
+	assert.NoError(t, os.WriteFile(path, []byte("p, role::reader, default, /article, GET\n"), 0644))
+	r, err = New(sets)
+	assert.NoError(t, err)
+	assert.Error(t, r.VerifyRequest("/article", "POST", "reader"))
+
+	// 执行器已缓存，文件变更后需要重新加载
+	assert.NoError(t, os.WriteFile(path, []byte("p, role::reader, default, /article, GET\np, role::reader, default, /article, POST\n"), 0644))
+	assert.Error(t, r.VerifyRequest("/article", "POST", "reader"))
+	assert.NoError(t, r.Reload())
+	assert.NoError(t, r.VerifyRequest("/article", "POST", "reader"))
+
+	t.Run("TestRbac_Reload_KeepOnFailure", func(t *testing.T) {
+		assert.NoError(t, os.Remove(path))
+		assert.Error(t, r.Reload())
+		assert.NoError(t, r.VerifyRequest("/article", "GET", "reader"))
+	})
+
+	t.Run("TestRbac_Reload_Concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+
+		assert.NoError(t, os.WriteFile(path, []byte("p, role::reader, default, /article, GET\n"), 0644))
+		for i := 0; i < 8; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, r.VerifyRequest("/article", "GET", "reader"))
+			}()
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, r.Reload())
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+// 验证请求复用执行器，单次验证的开销与调用次数无关
+func BenchmarkRbac_VerifyRequest(b *testing.B) {
+	var (
+		r = newBenchmarkRbac(b, 100)
+	)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := r.VerifyRequest("/resource/99", "GET", "role99"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRbac_VerifyRequestParallel(b *testing.B) {
+	var (
+		r = newBenchmarkRbac(b, 100)
+	)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := r.VerifyRequest("/resource/99", "GET", "role99"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// 重新加载需要重新读取授权政策并创建执行器
+func BenchmarkRbac_Reload(b *testing.B) {
+	var (
+		r = newBenchmarkRbac(b, 100)
+	)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := r.Reload(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// 创建包含指定数量授权政策的实例
+func newBenchmarkRbac(b *testing.B, size int) *Rbac {
+	var (
+		path   = filepath.Join(b.TempDir(), "policy.csv")
+		policy strings.Builder
+		r      *Rbac
+		err    error
+	)
+
+	for i := 0; i < size; i++ {
+		fmt.Fprintf(&policy, "p, role::role%d, default, /resource/%d, GET\n", i, i)
+	}
+	if err = os.WriteFile(path, []byte(policy.String()), 0644); err != nil {
+		b.Fatal(err)
+	}
+	if r, err = New(Settings{
+		TokenSignKey:   []byte("gVoiG1fbXf65osbjfi33MZre"),
+		TokenIssuer:    "lgcgo.com",
+		PolicyFilePath: path,
+	}); err != nil {
+		b.Fatal(err)
+	}
+
+	return r
+}
@@ -0,0 +1,180 @@
+package rbac
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// 过期吊销记录的默认清理间隔
+const revocationGCInterval = time.Minute
+
+// 吊销记录前缀
+const (
+	revokeTokenPrefix   = "jti:" // 吊销单个Token
+	revokeSubjectPrefix = "sub:" // 吊销主题在吊销时间之前签发的全部Token
+	revokeFamilyPrefix  = "fam:" // 吊销授权家族的全部Token
+	refreshUsedPrefix   = "use:" // 已使用的refresh_token
+)
+
+// 吊销存储接口
+// 记录在过期时间之后不再有效，实现方应自动清除过期记录
+type RevocationStore interface {
+	// 写入吊销记录，已存在时更新吊销时间；返回写入前记录是否已存在
+	Revoke(key string, expiresAt time.Time) (bool, error)
+	// 查询吊销记录，返回吊销时间
+	Lookup(key string) (time.Time, bool, error)
+}
+
+// 吊销记录
+type Revocation struct {
+	RevokedAt time.Time `json:"revokedAt"` // 吊销时间
+	ExpiresAt time.Time `json:"expiresAt"` // 过期时间，过期后记录被清除
+}
+
+// 内存吊销存储
+type MemoryRevocationStore struct {
+	mu         sync.RWMutex
+	records    map[string]Revocation
+	gcAt       time.Time     // 上一次清理时间
+	gcInterval time.Duration // 清理间隔
+}
+
+// 实例化内存吊销存储
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		records:    make(map[string]Revocation),
+		gcAt:       time.Now(),
+		gcInterval: revocationGCInterval,
+	}
+}
+
+// 写入吊销记录
+func (s *MemoryRevocationStore) Revoke(key string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.revoke(key, expiresAt), nil
+}
+
+// 查询吊销记录
+func (s *MemoryRevocationStore) Lookup(key string) (time.Time, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.records[key]
+	if !ok || time.Now().After(record.ExpiresAt) {
+		return time.Time{}, false, nil
+	}
+	return record.RevokedAt, true, nil
+}
+
+// 写入吊销记录，调用方需持有写锁
+func (s *MemoryRevocationStore) revoke(key string, expiresAt time.Time) bool {
+	var (
+		now = time.Now()
+	)
+
+	s.gc(now)
+	record, existed := s.records[key]
+	if existed && now.After(record.ExpiresAt) {
+		existed = false
+	}
+	// 重复吊销时延长过期时间，保证覆盖两次吊销之间签发的Token
+	if !existed || expiresAt.After(record.ExpiresAt) {
+		record.ExpiresAt = expiresAt
+	}
+	record.RevokedAt = now
+	s.records[key] = record
+
+	return existed
+}
+
+// 定期清理过期记录，调用方需持有写锁
+func (s *MemoryRevocationStore) gc(now time.Time) {
+	if now.Sub(s.gcAt) < s.gcInterval {
+		return
+	}
+	s.purge(now)
+}
+
+// 清除在指定时间已过期的记录，调用方需持有写锁
+func (s *MemoryRevocationStore) purge(now time.Time) {
+	for key, record := range s.records {
+		if now.After(record.ExpiresAt) {
+			delete(s.records, key)
+		}
+	}
+	s.gcAt = now
+}
+
+// 文件吊销存储，记录以JSON格式保存，每次写入后原子替换文件
+type FileRevocationStore struct {
+	MemoryRevocationStore
+	path string
+}
+
+// 实例化文件吊销存储，文件不存在时自动创建
+func NewFileRevocationStore(path string) (*FileRevocationStore, error) {
+	var (
+		store = &FileRevocationStore{
+			MemoryRevocationStore: MemoryRevocationStore{
+				records:    make(map[string]Revocation),
+				gcAt:       time.Now(),
+				gcInterval: revocationGCInterval,
+			},
+			path: path,
+		}
+		data []byte
+		err  error
+	)
+
+	if path == "" {
+		return nil, errors.New(ErrorRevocationFilePathInvalid)
+	}
+	if data, err = os.ReadFile(path); err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err = json.Unmarshal(data, &store.records); err != nil {
+			return nil, err
+		}
+	}
+	// 启动时清理已过期的记录，尚未过期的记录继续有效
+	store.purge(time.Now())
+
+	return store, nil
+}
+
+// 写入吊销记录并保存文件，保存失败时撤销内存中的记录，重试不会被视为记录已存在
+func (s *FileRevocationStore) Revoke(key string, expiresAt time.Time) (bool, error) {
+	var (
+		existed bool
+		data    []byte
+		err     error
+	)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, had := s.records[key]
+	existed = s.revoke(key, expiresAt)
+	if data, err = json.Marshal(s.records); err == nil {
+		err = writeFileAtomic(s.path, data, 0600)
+	}
+	if err != nil {
+		if had {
+			s.records[key] = prev
+		} else {
+			delete(s.records, key)
+		}
+		return false, err
+	}
+
+	return existed, nil
+}
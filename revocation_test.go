@@ -0,0 +1,251 @@
+package rbac
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pkg "github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryRevocationStore(t *testing.T) {
+	var (
+		store = NewMemoryRevocationStore()
+	)
+
+	existed, err := store.Revoke("jti:a", time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.False(t, existed)
+	existed, _ = store.Revoke("jti:a", time.Now().Add(time.Hour))
+	assert.True(t, existed)
+
+	_, ok, err := store.Lookup("jti:a")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	t.Run("TestMemoryRevocationStore_GC", func(t *testing.T) {
+		store.gcInterval = 0
+
+		store.Revoke("jti:b", time.Now().Add(-time.Second))
+		_, ok, _ := store.Lookup("jti:b")
+		assert.False(t, ok)
+
+		store.Revoke("jti:c", time.Now().Add(time.Hour))
+		assert.Len(t, store.records, 2)
+	})
+}
+
+func TestFileRevocationStore(t *testing.T) {
+	var (
+		path = filepath.Join(t.TempDir(), "revocation.json")
+	)
+
+	store, err := NewFileRevocationStore(path)
+	assert.NoError(t, err)
+	_, err = store.Revoke("jti:a", time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+
+	// 重新打开后记录仍然有效
+	store, err = NewFileRevocationStore(path)
+	assert.NoError(t, err)
+	_, ok, err := store.Lookup("jti:a")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	t.Run("TestFileRevocationStore_ShortTTL", func(t *testing.T) {
+		// 一分钟内过期的记录在重新打开后仍然有效
+		_, err = store.Revoke("jti:b", time.Now().Add(30*time.Second))
+		assert.NoError(t, err)
+		store, err = NewFileRevocationStore(path)
+		assert.NoError(t, err)
+		_, ok, err := store.Lookup("jti:b")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		// 已过期的记录在重新打开时清除
+		_, err = store.Revoke("jti:c", time.Now().Add(-time.Second))
+		assert.NoError(t, err)
+		store, err = NewFileRevocationStore(path)
+		assert.NoError(t, err)
+		assert.NotContains(t, store.records, "jti:c")
+	})
+
+	t.Run("TestFileRevocationStore_SaveFailed", func(t *testing.T) {
+		var (
+			dir  = filepath.Join(t.TempDir(), "store")
+			path = filepath.Join(dir, "revocation.json")
+		)
+
+		assert.NoError(t, os.Mkdir(dir, 0700))
+		store, err := NewFileRevocationStore(path)
+		assert.NoError(t, err)
+
+		// 保存失败时不保留记录，恢复后重试视为首次写入
+		assert.NoError(t, os.RemoveAll(dir))
+		_, err = store.Revoke("use:a", time.Now().Add(time.Hour))
+		assert.Error(t, err)
+		_, ok, err := store.Lookup("use:a")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		assert.NoError(t, os.Mkdir(dir, 0700))
+		existed, err := store.Revoke("use:a", time.Now().Add(time.Hour))
+		assert.NoError(t, err)
+		assert.False(t, existed)
+		existed, err = store.Revoke("use:a", time.Now().Add(time.Hour))
+		assert.NoError(t, err)
+		assert.True(t, existed)
+	})
+
+	t.Run("TestRbac_RevokeToken_Restart", func(t *testing.T) {
+		var (
+			sets = Settings{
+				TokenSignKey:           []byte("gVoiG1fbXf65osbjfi33MZre"),
+				AccessTokenExpireTime:  30 * time.Second,
+				RefreshTokenExpireTime: 50 * time.Second,
+			}
+			r     *Rbac
+			token *Token
+		)
+
+		sets.RevocationStore = store
+		r, err = New(sets)
+		assert.NoError(t, err)
+		token, err = r.Authorization("uid001", "admin")
+		assert.NoError(t, err)
+		assert.NoError(t, r.RevokeToken(token.AccessToken))
+
+		// 重启后吊销仍然有效
+		sets.RevocationStore, err = NewFileRevocationStore(path)
+		assert.NoError(t, err)
+		r, err = New(sets)
+		assert.NoError(t, err)
+		_, err = r.VerifyToken(token.AccessToken)
+		assert.Error(t, err)
+		assert.Equal(t, ErrorTokenRevoked, err.Error())
+	})
+}
+
+func TestRbac_RevokeToken(t *testing.T) {
+	var (
+		sets = Settings{
+			TokenSignKey: []byte("gVoiG1fbXf65osbjfi33MZre"),
+			TokenIssuer:  "lgcgo.com",
+		}
+		r     *Rbac
+		token *Token
+		err   error
+	)
+
+	r, _ = New(sets)
+	token, _ = r.Authorization("uid001", "admin")
+
+	assert.NoError(t, r.RevokeToken(token.AccessToken))
+	_, err = r.VerifyToken(token.AccessToken)
+	assert.Error(t, err)
+	assert.Equal(t, ErrorTokenRevoked, err.Error())
+
+	// 吊销access_token不影响refresh_token
+	_, err = r.RefreshAuthorization(token.RefreshToken)
+	assert.NoError(t, err)
+
+	assert.NoError(t, r.RevokeToken(token.RefreshToken))
+	_, err = r.RefreshAuthorization(token.RefreshToken)
+	assert.Error(t, err)
+	assert.Equal(t, ErrorTokenRevoked, err.Error())
+}
+
+func TestRbac_RevokeSubject(t *testing.T) {
+	var (
+		sets = Settings{
+			TokenSignKey: []byte("gVoiG1fbXf65osbjfi33MZre"),
+			TokenIssuer:  "lgcgo.com",
+		}
+		r     *Rbac
+		token *Token
+		other *Token
+		err   error
+	)
+
+	r, _ = New(sets)
+	token, _ = r.Authorization("uid001", "admin")
+	other, _ = r.Authorization("uid002", "admin")
+
+	assert.NoError(t, r.RevokeSubject("uid001"))
+	_, err = r.VerifyToken(token.AccessToken)
+	assert.Error(t, err)
+	assert.Equal(t, ErrorTokenRevoked, err.Error())
+	_, err = r.RefreshAuthorization(token.RefreshToken)
+	assert.Error(t, err)
+	assert.Equal(t, ErrorTokenRevoked, err.Error())
+
+	_, err = r.VerifyToken(other.AccessToken)
+	assert.NoError(t, err)
+
+	t.Run("TestRbac_RevokeSubject_Reauthorize", func(t *testing.T) {
+		// 吊销后立即重新登录，新Token与吊销在同一秒内也有效，且不需要等待
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, r.RevokeSubject("uid003"))
+			renewed, err := r.Authorization("uid003", "admin")
+			assert.NoError(t, err)
+			_, err = r.VerifyToken(renewed.AccessToken)
+			assert.NoError(t, err)
+		}
+		assert.Less(t, int64(time.Since(start)), int64(100*time.Millisecond))
+	})
+
+	t.Run("TestRbac_RevokeSubject_IssuedAtSeconds", func(t *testing.T) {
+		// 没有ins声明的Token按iat判断，吊销所在秒内签发的视为已吊销
+		assert.NoError(t, r.RevokeSubject("uid004"))
+		claims := &Claims{RegisteredClaims: pkg.RegisteredClaims{
+			Subject:  "uid004",
+			IssuedAt: pkg.NewNumericDate(time.Now()),
+		}}
+		err := r.checkRevoked(claims)
+		assert.Error(t, err)
+		assert.Equal(t, ErrorTokenRevoked, err.Error())
+
+		claims.IssuedAtNs = time.Now().UnixNano()
+		assert.NoError(t, r.checkRevoked(claims))
+	})
+}
+
+func TestRbac_RefreshAuthorizationReuse(t *testing.T) {
+	var (
+		sets = Settings{
+			TokenSignKey: []byte("gVoiG1fbXf65osbjfi33MZre"),
+			TokenIssuer:  "lgcgo.com",
+		}
+		r       *Rbac
+		token   *Token
+		renewed *Token
+		err     error
+	)
+
+	r, _ = New(sets)
+	token, _ = r.Authorization("uid001", "admin")
+
+	renewed, err = r.RefreshAuthorization(token.RefreshToken)
+	assert.NoError(t, err)
+
+	// 重复使用已使用的refresh_token
+	_, err = r.RefreshAuthorization(token.RefreshToken)
+	assert.Error(t, err)
+	assert.Equal(t, ErrorTokenRefreshReused, err.Error())
+
+	// 整个授权家族被吊销
+	_, err = r.RefreshAuthorization(renewed.RefreshToken)
+	assert.Error(t, err)
+	assert.Equal(t, ErrorTokenRevoked, err.Error())
+	_, err = r.VerifyToken(renewed.AccessToken)
+	assert.Error(t, err)
+	assert.Equal(t, ErrorTokenRevoked, err.Error())
+
+	// 其它授权家族不受影响
+	token, _ = r.Authorization("uid001", "admin")
+	_, err = r.RefreshAuthorization(token.RefreshToken)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,85 @@
+package rbac
+
+import (
+	"time"
+)
+
+// 超级管理员设置
+// 超级管理员不经过授权政策直接放行，未设置角色名称时为 root
+type SuperRole struct {
+	Roles    []string                 // 超级管理员角色名称，默认为 root
+	Domains  []string                 // 生效的域，为空时在所有域生效
+	Disabled bool                     // 关闭超级管理员，关闭后与普通角色一样按授权政策验证
+	Hook     func(*SuperRoleDecision) // 放行回调，每次超级管理员放行时调用，用于审计
+}
+
+// 超级管理员放行记录
+type SuperRoleDecision struct {
+	Role   string    // 角色
+	Domain string    // 域
+	Path   string    // 资源路径
+	Method string    // 请求方法
+	Time   time.Time // 放行时间
+}
+
+// 获取超级管理员角色名称
+func (s *SuperRole) roles() []string {
+	if len(s.Roles) == 0 {
+		return []string{rootSubject}
+	}
+	return s.Roles
+}
+
+// 获取挂载顶级角色的超级管理员主体名称
+func (s *SuperRole) subject() string {
+	return roleSubject(s.roles()[0])
+}
+
+// 判断是否为在指定域生效的超级管理员
+func (s *SuperRole) match(subject, domain string) bool {
+	if s.Disabled {
+		return false
+	}
+	if !containsString(s.Domains, domain) && len(s.Domains) > 0 {
+		return false
+	}
+	for _, role := range s.roles() {
+		if roleSubject(role) == subject {
+			return true
+		}
+	}
+	return false
+}
+
+// 超级管理员放行，返回是否放行
+func (s *SuperRole) bypass(p *UriPolicy) bool {
+	if !s.match(p.Role, p.Domain) {
+		return false
+	}
+	if s.Hook != nil {
+		s.Hook(&SuperRoleDecision{
+			Role:   p.Role,
+			Domain: p.Domain,
+			Path:   p.Path,
+			Method: p.Method,
+			Time:   time.Now(),
+		})
+	}
+	return true
+}
+
+// 判断是否为挂载顶级角色的主体，包含当前的超级管理员与默认的 root
+// 修改超级管理员前写入的 root 关系同样只记录角色层级
+func isTopLevelParent(subject, root string) bool {
+	return subject == root || subject == rootSubject
+}
+
+// 判断字符串切片是否包含指定字符串
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package rbac
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRbac_SuperRole(t *testing.T) {
+	var (
+		path   = filepath.Join(t.TempDir(), "policy.csv")
+		policy = "p, role::admin, default, /user, GET\n" +
+			"g, root, role::admin, default\n"
+	)
+
+	assert.NoError(t, os.WriteFile(path, []byte(policy), 0644))
+	newRbac := func(superRole SuperRole) *Rbac {
+		r, err := New(Settings{
+			TokenSignKey:   []byte("gVoiG1fbXf65osbjfi33MZre"),
+			PolicyFilePath: path,
+			SuperRole:      superRole,
+		})
+		assert.NoError(t, err)
+		return r
+	}
+
+	t.Run("TestRbac_SuperRole_Default", func(t *testing.T) {
+		r := newRbac(SuperRole{})
+		assert.NoError(t, r.VerifyRequest("/user", "DELETE", "root"))
+		assert.NoError(t, r.VerifyDomainRequest("manager", "/user", "DELETE", "root"))
+	})
+
+	t.Run("TestRbac_SuperRole_Custom", func(t *testing.T) {
+		var decisions []*SuperRoleDecision
+
+		r := newRbac(SuperRole{
+			Roles:   []string{"sysadmin"},
+			Domains: []string{"manager"},
+			Hook: func(d *SuperRoleDecision) {
+				decisions = append(decisions, d)
+			},
+		})
+		assert.NoError(t, r.VerifyDomainRequest("manager", "/user", "DELETE", "sysadmin"))
+		// 只在指定的域生效
+		assert.Error(t, r.VerifyRequest("/user", "DELETE", "sysadmin"))
+		// root 不再是超级管理员，原有的 root 关系只记录顶级角色，不授予权限
+		assert.Error(t, r.VerifyRequest("/user", "GET", "root"))
+		assert.Error(t, r.VerifyRequest("/user", "DELETE", "root"))
+
+		// 挂载在超级管理员下的顶级角色不会在其它域授予超级管理员权限
+		assert.NoError(t, r.Casbin.AddRolePolicy(&RolePolicy{Role: "admin", Domain: "default"}))
+		assert.NoError(t, r.VerifyDomainRequest("manager", "/user", "DELETE", "sysadmin"))
+		assert.Error(t, r.VerifyRequest("/user", "GET", "sysadmin"))
+		assert.NoError(t, r.Reload())
+		assert.Error(t, r.VerifyRequest("/user", "GET", "sysadmin"))
+		assert.NoError(t, r.VerifyRequest("/user", "GET", "admin"))
+
+		assert.Len(t, decisions, 2)
+		assert.Equal(t, "role::sysadmin", decisions[0].Role)
+		assert.Equal(t, "manager", decisions[0].Domain)
+		assert.Equal(t, "/user", decisions[0].Path)
+		assert.Equal(t, "DELETE", decisions[0].Method)
+	})
+
+	t.Run("TestRbac_SuperRole_Disabled", func(t *testing.T) {
+		r := newRbac(SuperRole{Disabled: true})
+		// 关闭后不会经挂载的顶级角色获得权限
+		assert.Error(t, r.VerifyRequest("/user", "GET", "root"))
+		assert.NoError(t, r.VerifyRequest("/user", "GET", "admin"))
+		assert.Error(t, r.VerifyRequest("/user", "DELETE", "root"))
+		assert.Error(t, r.VerifyDomainRequest("manager", "/user", "GET", "root"))
+	})
+}
+
+func TestRbac_SuperRole_LegacyRoot(t *testing.T) {
+	var (
+		path   = filepath.Join(t.TempDir(), "policy.csv")
+		policy = "p, role::a, default, /a, GET\n" +
+			"p, role::b, default, /b, GET\n" +
+			"g, root, role::a, default\n" +
+			"g, root, role::b, default\n"
+	)
+
+	assert.NoError(t, os.WriteFile(path, []byte(policy), 0644))
+	r, err := New(Settings{
+		TokenSignKey:   []byte("gVoiG1fbXf65osbjfi33MZre"),
+		PolicyFilePath: path,
+		SuperRole:      SuperRole{Roles: []string{"sysadmin"}},
+	})
+	assert.NoError(t, err)
+
+	// 更换超级管理员后，root 不会经原有的关系获得顶级角色的权限
+	assert.Error(t, r.VerifyRequest("/a", "GET", "root"))
+	assert.Error(t, r.VerifyRequest("/b", "GET", "root"))
+	assert.NoError(t, r.VerifyRequest("/a", "GET", "a"))
+	assert.NoError(t, r.VerifyRequest("/b", "GET", "sysadmin"))
+
+	d, err := r.ExplainRequest("", "/a", "GET", "root")
+	assert.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.Empty(t, d.Matched)
+}
+
+func TestRolePolicy_FormatLine(t *testing.T) {
+	var (
+		p         = RolePolicy{Role: "admin", Domain: "default"}
+		superRole = SuperRole{Roles: []string{"sysadmin"}}
+	)
+
+	assert.Equal(t, "g, root, role::admin, default", p.FormatLine())
+	assert.Equal(t, "g, role::sysadmin, role::admin, default", p.formatLine(superRole.subject()))
+	p.ParentRole = "manager"
+	assert.Equal(t, "g, role::manager, role::admin, default", p.FormatLine())
+}
@@ -0,0 +1,159 @@
+package rbac
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// 默认的政策文件检查间隔
+const defaultPolicyWatchInterval = 5 * time.Second
+
+// 政策文件监视选项
+type PolicyWatchOptions struct {
+	Interval time.Duration // 检查间隔，默认5秒
+	OnReload func()        // 重新加载成功回调
+	OnError  func(error)   // 重新加载失败回调，失败时继续使用原有的授权政策
+}
+
+// 政策文件监视器
+// 定期检查政策文件，内容变更时先校验新文件，校验通过后重新加载并替换执行器
+type PolicyWatcher struct {
+	casbin   *Casbin
+	options  PolicyWatchOptions
+	modTime  time.Time
+	size     int64
+	sum      [sha256.Size]byte
+	retry    bool   // 上一次读取或加载失败，下次检查时重试
+	lastErr  string // 上一次报告的错误，相同错误不重复报告
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// 监视政策文件，文件变更后自动重新加载
+func (c *Casbin) WatchPolicyFile(opts PolicyWatchOptions) (*PolicyWatcher, error) {
+	var (
+		w = &PolicyWatcher{
+			casbin:  c,
+			options: opts,
+			stop:    make(chan struct{}),
+			done:    make(chan struct{}),
+		}
+		info os.FileInfo
+		data []byte
+		err  error
+	)
+
+	if c.PolicyFilePath == "" {
+		return nil, errors.New(ErrorPolicyFilePathInvalid)
+	}
+	if w.options.Interval <= 0 {
+		w.options.Interval = defaultPolicyWatchInterval
+	}
+	// 记录当前文件状态，作为变更的比较基准
+	if info, err = os.Stat(c.PolicyFilePath); err != nil {
+		return nil, err
+	}
+	if data, err = os.ReadFile(c.PolicyFilePath); err != nil {
+		return nil, err
+	}
+	w.modTime, w.size, w.sum = info.ModTime(), info.Size(), sha256.Sum256(data)
+
+	go w.run()
+
+	return w, nil
+}
+
+// 监视政策文件，文件变更后自动重新加载
+func (r *Rbac) WatchPolicyFile(opts PolicyWatchOptions) (*PolicyWatcher, error) {
+	return r.Casbin.WatchPolicyFile(opts)
+}
+
+// 停止监视，可以重复调用
+func (w *PolicyWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+}
+
+// 定期检查政策文件
+func (w *PolicyWatcher) run() {
+	var (
+		ticker = time.NewTicker(w.options.Interval)
+	)
+
+	defer close(w.done)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+// 检查政策文件是否变更，变更时校验并重新加载
+func (w *PolicyWatcher) check() {
+	var (
+		path = w.casbin.PolicyFilePath
+		info os.FileInfo
+		data []byte
+		err  error
+	)
+
+	if info, err = os.Stat(path); err != nil {
+		w.report(err)
+		return
+	}
+	// 修改时间与大小都未变化时不读取文件，上一次读取或加载失败时重试
+	if info.ModTime().Equal(w.modTime) && info.Size() == w.size && !w.retry {
+		return
+	}
+	if data, err = os.ReadFile(path); err != nil {
+		w.retry = true
+		w.report(err)
+		return
+	}
+	w.modTime, w.size, w.retry = info.ModTime(), info.Size(), false
+	// 内容未变化，或已校验过的不合法内容
+	sum := sha256.Sum256(data)
+	if sum == w.sum {
+		return
+	}
+
+	// 校验新文件，不合法时保留原有的授权政策
+	if _, _, err = ParsePolicies(bytes.NewReader(data)); err != nil {
+		w.sum = sum
+		w.report(err)
+		return
+	}
+	if err = w.casbin.Reload(); err != nil {
+		w.retry = true
+		w.report(err)
+		return
+	}
+	w.sum = sum
+	w.lastErr = ""
+	if w.options.OnReload != nil {
+		w.options.OnReload()
+	}
+}
+
+// 报告错误，相同的错误只报告一次
+func (w *PolicyWatcher) report(err error) {
+	if err.Error() == w.lastErr {
+		return
+	}
+	w.lastErr = err.Error()
+	if w.options.OnError != nil {
+		w.options.OnError(err)
+	}
+}
@@ -0,0 +1,75 @@
+package rbac
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRbac_WatchPolicyFile(t *testing.T) {
+	var (
+		path = filepath.Join(t.TempDir(), "policy.csv")
+		sets = Settings{
+			TokenSignKey:   []byte("gVoiG1fbXf65osbjfi33MZre"),
+			PolicyFilePath: path,
+		}
+		reloaded = make(chan struct{}, 1)
+		failed   = make(chan error, 1)
+		r        *Rbac
+		w        *PolicyWatcher
+		err      error
+	)
+
+	assert.NoError(t, os.WriteFile(path, []byte("p, role::reader, default, /article, GET\n"), 0644))
+	r, err = New(sets)
+	assert.NoError(t, err)
+	w, err = r.WatchPolicyFile(PolicyWatchOptions{
+		Interval: 10 * time.Millisecond,
+		OnReload: func() { reloaded <- struct{}{} },
+		OnError:  func(err error) { failed <- err },
+	})
+	assert.NoError(t, err)
+	defer w.Stop()
+
+	t.Run("TestRbac_WatchPolicyFile_Reload", func(t *testing.T) {
+		assert.Error(t, r.VerifyRequest("/article", "POST", "reader"))
+		// 原子替换政策文件，避免监视器读取到写入一半的文件
+		assert.NoError(t, writeFileAtomic(path, []byte("p, role::reader, default, /article, GET|POST\n"), 0644))
+		select {
+		case <-reloaded:
+		case err := <-failed:
+			t.Fatal(err)
+		case <-time.After(2 * time.Second):
+			t.Fatal("policy file not reloaded")
+		}
+		assert.NoError(t, r.VerifyRequest("/article", "POST", "reader"))
+	})
+
+	t.Run("TestRbac_WatchPolicyFile_Invalid", func(t *testing.T) {
+		var lineErr *PolicyLineError
+
+		assert.NoError(t, writeFileAtomic(path, []byte("p, role::reader, default, /article\n"), 0644))
+		select {
+		case err := <-failed:
+			assert.True(t, errors.As(err, &lineErr))
+			assert.Equal(t, 1, lineErr.Line)
+		case <-reloaded:
+			t.Fatal("invalid policy file reloaded")
+		case <-time.After(2 * time.Second):
+			t.Fatal("invalid policy file not reported")
+		}
+		// 保留原有的授权政策
+		assert.NoError(t, r.VerifyRequest("/article", "POST", "reader"))
+	})
+
+	t.Run("TestRbac_WatchPolicyFile_Stop", func(t *testing.T) {
+		w.Stop()
+		assert.NoError(t, os.WriteFile(path, []byte("p, role::reader, default, /article, GET\n"), 0644))
+		time.Sleep(50 * time.Millisecond)
+		assert.NoError(t, r.VerifyRequest("/article", "POST", "reader"))
+	})
+}
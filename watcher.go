@@ -0,0 +1,434 @@
+package rbac
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// 政策变更通知接口，与Casbin的 persist.Watcher 兼容，可以直接使用Casbin生态中的Watcher(如Redis、etcd)
+// 实现 persist.WatcherEx 时发送增量变更，否则通知其它节点全量重新加载
+type Watcher = persist.Watcher
+
+// 通知操作
+const (
+	watcherOpReload = "reload" // 全量重新加载
+	watcherOpAdd    = "add"    // 添加政策
+	watcherOpRemove = "remove" // 移除政策
+)
+
+// 默认的通知文件检查间隔
+const defaultFileWatcherInterval = time.Second
+
+// 默认的通知文件最大大小，超过后写入通知前替换为空文件
+const defaultFileWatcherMaxSize = 1 << 20
+
+// 通知消息
+type watcherMessage struct {
+	Origin string     `json:"origin,omitempty"` // 发送方标识，不通知发送方自身
+	Op     string     `json:"op"`               // 操作
+	Sec    string     `json:"sec,omitempty"`    // 政策类型，p或g
+	Rules  [][]string `json:"rules,omitempty"`  // 变更的政策规则
+}
+
+// 解析通知消息，非本包格式的消息(如其它Watcher实现)返回false
+func decodeWatcherMessage(data string) (*watcherMessage, bool) {
+	var (
+		msg watcherMessage
+	)
+
+	if err := json.Unmarshal([]byte(data), &msg); err != nil || msg.Op == "" {
+		return nil, false
+	}
+	return &msg, true
+}
+
+// 实现 persist.WatcherEx 的通知方法，由具体的Watcher提供发送函数
+type watcherEx struct {
+	publish func(msg *watcherMessage) error
+}
+
+// 通知全量重新加载
+func (w *watcherEx) Update() error {
+	return w.publish(&watcherMessage{Op: watcherOpReload})
+}
+
+// 通知添加政策
+func (w *watcherEx) UpdateForAddPolicy(sec, ptype string, params ...string) error {
+	return w.UpdateForAddPolicies(sec, ptype, params)
+}
+
+// 通知移除政策
+func (w *watcherEx) UpdateForRemovePolicy(sec, ptype string, params ...string) error {
+	return w.UpdateForRemovePolicies(sec, ptype, params)
+}
+
+// 按条件移除政策，通知全量重新加载
+func (w *watcherEx) UpdateForRemoveFilteredPolicy(sec, ptype string, fieldIndex int, fieldValues ...string) error {
+	return w.Update()
+}
+
+// 保存全部政策，通知全量重新加载
+func (w *watcherEx) UpdateForSavePolicy(model model.Model) error {
+	return w.Update()
+}
+
+// 通知批量添加政策
+func (w *watcherEx) UpdateForAddPolicies(sec string, ptype string, rules ...[]string) error {
+	return w.publish(&watcherMessage{Op: watcherOpAdd, Sec: sec, Rules: rules})
+}
+
+// 通知批量移除政策
+func (w *watcherEx) UpdateForRemovePolicies(sec string, ptype string, rules ...[]string) error {
+	return w.publish(&watcherMessage{Op: watcherOpRemove, Sec: sec, Rules: rules})
+}
+
+// 进程内的通知中心，同一中心的Watcher互相通知，用于测试或同一进程中的多个实例
+type MemoryWatcherHub struct {
+	mu       sync.RWMutex
+	watchers map[*MemoryWatcher]struct{}
+}
+
+// 实例化进程内的通知中心
+func NewMemoryWatcherHub() *MemoryWatcherHub {
+	return &MemoryWatcherHub{
+		watchers: make(map[*MemoryWatcher]struct{}),
+	}
+}
+
+// 创建连接到通知中心的Watcher
+func (h *MemoryWatcherHub) NewWatcher() *MemoryWatcher {
+	var (
+		w = &MemoryWatcher{hub: h}
+	)
+
+	w.watcherEx.publish = w.publish
+	h.mu.Lock()
+	h.watchers[w] = struct{}{}
+	h.mu.Unlock()
+
+	return w
+}
+
+// 进程内的Watcher，通知同步送达同一通知中心的其它Watcher
+type MemoryWatcher struct {
+	watcherEx
+	hub      *MemoryWatcherHub
+	mu       sync.RWMutex
+	callback func(string)
+}
+
+// 设置收到通知时的回调
+func (w *MemoryWatcher) SetUpdateCallback(callback func(string)) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.callback = callback
+	return nil
+}
+
+// 断开与通知中心的连接
+func (w *MemoryWatcher) Close() {
+	w.hub.mu.Lock()
+	delete(w.hub.watchers, w)
+	w.hub.mu.Unlock()
+}
+
+// 发送通知到其它Watcher
+func (w *MemoryWatcher) publish(msg *watcherMessage) error {
+	var (
+		callbacks []func(string)
+	)
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	w.hub.mu.RLock()
+	for other := range w.hub.watchers {
+		if other == w {
+			continue
+		}
+		other.mu.RLock()
+		if other.callback != nil {
+			callbacks = append(callbacks, other.callback)
+		}
+		other.mu.RUnlock()
+	}
+	w.hub.mu.RUnlock()
+
+	for _, callback := range callbacks {
+		callback(string(data))
+	}
+	return nil
+}
+
+// 基于共享通知文件的Watcher，用于同一主机上的多个进程
+// 每条通知以一行JSON追加写入通知文件，各进程定期读取新增的行；
+// 通知文件超过最大大小时由写入方替换为空文件，通知文件被截断或替换时，通知全量重新加载
+type FileWatcher struct {
+	watcherEx
+	path     string
+	origin   string
+	interval time.Duration
+	mu       sync.Mutex
+	callback func(string)
+	maxSize  int64       // 通知文件的最大大小
+	info     os.FileInfo // 正在读取的通知文件，用于发现文件被替换
+	offset   int64       // 已读取的位置
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// 实例化基于共享通知文件的Watcher，文件不存在时自动创建，从文件末尾开始读取通知
+func NewFileWatcher(path string, interval time.Duration) (*FileWatcher, error) {
+	var (
+		w = &FileWatcher{
+			path:     path,
+			interval: interval,
+			maxSize:  defaultFileWatcherMaxSize,
+			stop:     make(chan struct{}),
+			done:     make(chan struct{}),
+		}
+		file *os.File
+		info os.FileInfo
+		err  error
+	)
+
+	if path == "" {
+		return nil, errors.New(ErrorWatcherFilePathInvalid)
+	}
+	if w.interval <= 0 {
+		w.interval = defaultFileWatcherInterval
+	}
+	if w.origin, err = newTokenId(); err != nil {
+		return nil, err
+	}
+	if file, err = os.OpenFile(path, os.O_RDONLY|os.O_CREATE, 0644); err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if info, err = file.Stat(); err != nil {
+		return nil, err
+	}
+	w.info = info
+	w.offset = info.Size()
+	w.watcherEx.publish = w.publish
+
+	go w.run()
+
+	return w, nil
+}
+
+// 设置收到通知时的回调
+func (w *FileWatcher) SetUpdateCallback(callback func(string)) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.callback = callback
+	return nil
+}
+
+// 设置通知文件的最大大小，默认1MB
+// 写入通知时文件超过最大大小则先替换为空文件，其它进程发现文件被替换后全量重新加载
+func (w *FileWatcher) SetMaxSize(size int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.maxSize = size
+}
+
+// 停止读取通知，可以重复调用
+func (w *FileWatcher) Close() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+}
+
+// 追加写入通知，单次写入一整行，多个进程同时写入时不会交错
+func (w *FileWatcher) publish(msg *watcherMessage) error {
+	msg.Origin = w.origin
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	// 通知文件过大时替换为空文件，替换前的通知由读取方全量重新加载覆盖
+	w.mu.Lock()
+	maxSize := w.maxSize
+	w.mu.Unlock()
+	if info, err := os.Stat(w.path); err == nil && maxSize > 0 && info.Size() >= maxSize {
+		if err = writeFileAtomic(w.path, nil, 0644); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// 定期读取新增的通知
+func (w *FileWatcher) run() {
+	var (
+		ticker = time.NewTicker(w.interval)
+	)
+
+	defer close(w.done)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.poll()
+		}
+	}
+}
+
+// 读取新增的通知并回调，忽略自身发送的通知
+func (w *FileWatcher) poll() {
+	var (
+		messages []string
+	)
+
+	w.mu.Lock()
+	callback := w.callback
+	w.mu.Unlock()
+
+	file, err := os.Open(w.path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return
+	}
+	// 通知文件被替换或截断，无法得知遗漏的通知，全量重新加载
+	if !os.SameFile(info, w.info) || info.Size() < w.offset {
+		w.info = info
+		w.offset = info.Size()
+		if callback != nil {
+			callback(watcherOpReload)
+		}
+		return
+	}
+	if info.Size() == w.offset {
+		return
+	}
+	if _, err = file.Seek(w.offset, io.SeekStart); err != nil {
+		return
+	}
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		// 不完整的行等待下次读取
+		if err != nil {
+			break
+		}
+		w.offset += int64(len(line))
+		msg, ok := decodeWatcherMessage(string(line))
+		if ok && msg.Origin == w.origin {
+			continue
+		}
+		messages = append(messages, string(line))
+	}
+
+	if callback != nil {
+		for _, msg := range messages {
+			callback(msg)
+		}
+	}
+}
+
+// 设置政策变更通知
+// 本实例变更授权政策后通知其它节点，收到其它节点的通知时同步变更：
+// 增量通知直接变更执行器，其它通知或增量同步失败时全量重新加载
+func (c *Casbin) SetWatcher(w Watcher) error {
+	if err := w.SetUpdateCallback(c.onWatcherUpdate); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.watcher = w
+	c.mu.Unlock()
+
+	return nil
+}
+
+// 通知其它节点授权政策已变更
+func (c *Casbin) notifyWatcher(op, sec string, rules [][]string) error {
+	c.mu.RLock()
+	w := c.watcher
+	c.mu.RUnlock()
+
+	if w == nil {
+		return nil
+	}
+	var err error
+	if wx, ok := w.(persist.WatcherEx); ok && (op == watcherOpAdd || op == watcherOpRemove) {
+		if op == watcherOpAdd {
+			err = wx.UpdateForAddPolicies(sec, sec, rules...)
+		} else {
+			err = wx.UpdateForRemovePolicies(sec, sec, rules...)
+		}
+	} else {
+		err = w.Update()
+	}
+	if err != nil {
+		return &WatcherNotifyError{Err: err}
+	}
+	return nil
+}
+
+// 通知其它节点失败
+// 本节点的政策变更已经生效并保存，不需要重试变更；其它节点可以通过 Reload 同步
+type WatcherNotifyError struct {
+	Err error // Watcher返回的错误
+}
+
+func (e *WatcherNotifyError) Error() string {
+	return ErrorWatcherNotifyFailed + ": " + e.Err.Error()
+}
+
+func (e *WatcherNotifyError) Unwrap() error {
+	return e.Err
+}
+
+// 收到其它节点的变更通知
+func (c *Casbin) onWatcherUpdate(data string) {
+	var (
+		err error
+	)
+
+	msg, ok := decodeWatcherMessage(data)
+	if ok && (msg.Op == watcherOpAdd || msg.Op == watcherOpRemove) && (msg.Sec == "p" || msg.Sec == "g") {
+		if err = c.update(msg.Op, msg.Sec, msg.Rules, false); err == nil {
+			return
+		}
+	}
+	// 全量重新加载，失败时继续使用原有的授权政策
+	if err = c.Reload(); err != nil && c.OnWatcherError != nil {
+		c.OnWatcherError(err)
+	}
+}
@@ -0,0 +1,159 @@
+package rbac
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryWatcher(t *testing.T) {
+	var (
+		dir    = t.TempDir()
+		hub    = NewMemoryWatcherHub()
+		reader = UriPolicy{Role: "reader", Domain: "default", Path: "/article", Method: "GET"}
+	)
+
+	// 两个节点使用各自的政策文件，只能通过通知同步
+	newNode := func(name string) *Rbac {
+		path := filepath.Join(dir, name+".csv")
+		assert.NoError(t, os.WriteFile(path, []byte("p, role::reader, default, /users, GET\n"), 0644))
+		r, err := New(Settings{
+			TokenSignKey:   []byte("gVoiG1fbXf65osbjfi33MZre"),
+			PolicyFilePath: path,
+			Watcher:        hub.NewWatcher(),
+		})
+		assert.NoError(t, err)
+		return r
+	}
+	a, b := newNode("a"), newNode("b")
+
+	t.Run("TestMemoryWatcher_Incremental", func(t *testing.T) {
+		assert.NoError(t, a.Casbin.AddUriPolicy(&reader))
+		assert.NoError(t, b.VerifyRequest("/article", "GET", "reader"))
+		// 增量同步不读取政策文件
+		data, _ := os.ReadFile(filepath.Join(dir, "b.csv"))
+		assert.NotContains(t, string(data), "/article")
+
+		assert.NoError(t, a.Casbin.RemoveUriPolicy(&reader))
+		assert.Error(t, b.VerifyRequest("/article", "GET", "reader"))
+	})
+
+	t.Run("TestMemoryWatcher_Reload", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "b.csv"), []byte("p, role::reader, default, /article, GET\n"), 0644))
+		assert.NoError(t, a.Casbin.SaveAllPolicyCsv(nil, nil))
+		assert.NoError(t, b.VerifyRequest("/article", "GET", "reader"))
+	})
+
+	t.Run("TestMemoryWatcher_UnknownMessage", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "b.csv"), []byte("p, role::reader, default, /users, GET\n"), 0644))
+		b.Casbin.onWatcherUpdate("policy changed")
+		assert.Error(t, b.VerifyRequest("/article", "GET", "reader"))
+	})
+}
+
+func TestFileWatcher(t *testing.T) {
+	var (
+		dir    = t.TempDir()
+		notify = filepath.Join(dir, "policy.notify")
+		path   = filepath.Join(dir, "policy.csv")
+		reader = UriPolicy{Role: "reader", Domain: "default", Path: "/article", Method: "GET"}
+		nodes  []*Rbac
+	)
+
+	assert.NoError(t, os.WriteFile(path, []byte("p, role::reader, default, /users, GET\n"), 0644))
+	for i := 0; i < 2; i++ {
+		w, err := NewFileWatcher(notify, 10*time.Millisecond)
+		assert.NoError(t, err)
+		defer w.Close()
+		r, err := New(Settings{
+			TokenSignKey:   []byte("gVoiG1fbXf65osbjfi33MZre"),
+			PolicyFilePath: path,
+			Watcher:        w,
+		})
+		assert.NoError(t, err)
+		nodes = append(nodes, r)
+	}
+
+	t.Run("TestFileWatcher_Incremental", func(t *testing.T) {
+		assert.NoError(t, nodes[0].Casbin.AddUriPolicy(&reader))
+		assert.Eventually(t, func() bool {
+			return nodes[1].VerifyRequest("/article", "GET", "reader") == nil
+		}, 2*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("TestFileWatcher_Rotated", func(t *testing.T) {
+		var (
+			editor = UriPolicy{Role: "editor", Domain: "default", Path: "/article", Method: "POST"}
+		)
+
+		// 通知文件超过最大大小时替换为空文件，不会无限增长
+		w, err := NewFileWatcher(notify, time.Hour)
+		assert.NoError(t, err)
+		defer w.Close()
+		w.SetMaxSize(1)
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, w.Update())
+			info, err := os.Stat(notify)
+			assert.NoError(t, err)
+			assert.Less(t, info.Size(), int64(256))
+		}
+
+		// 其它节点发现文件被替换后全量重新加载
+		assert.NoError(t, nodes[0].Casbin.AddUriPolicy(&editor))
+		assert.NoError(t, w.Update())
+		assert.Eventually(t, func() bool {
+			return nodes[1].VerifyRequest("/article", "POST", "editor") == nil
+		}, 2*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("TestFileWatcher_Truncated", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(path, []byte("p, role::reader, default, /users, GET\n"), 0644))
+		assert.NoError(t, os.Truncate(notify, 0))
+		assert.Eventually(t, func() bool {
+			return nodes[1].VerifyRequest("/article", "GET", "reader") != nil
+		}, 2*time.Second, 10*time.Millisecond)
+	})
+}
+
+// 通知总是失败的Watcher
+type failingWatcher struct{}
+
+func (w *failingWatcher) SetUpdateCallback(func(string)) error { return nil }
+func (w *failingWatcher) Update() error                        { return errors.New("watcher down") }
+func (w *failingWatcher) Close()                               {}
+
+func TestCasbin_WatcherNotifyFailed(t *testing.T) {
+	var (
+		path   = filepath.Join(t.TempDir(), "policy.csv")
+		c      = NewCasbin(path)
+		reader = UriPolicy{Role: "reader", Domain: "default", Path: "/article", Method: "GET"}
+		notify *WatcherNotifyError
+		err    error
+	)
+
+	assert.NoError(t, os.WriteFile(path, []byte("p, role::reader, default, /users, GET\n"), 0644))
+	assert.NoError(t, c.SetWatcher(&failingWatcher{}))
+
+	// 通知失败时变更已经生效并保存，与变更失败区分
+	err = c.AddUriPolicy(&reader)
+	assert.True(t, errors.As(err, &notify))
+	assert.Equal(t, "watcher down", notify.Err.Error())
+	assert.Equal(t, ErrorWatcherNotifyFailed+": watcher down", err.Error())
+	assert.NoError(t, c.VerifyUriPolicy(&UriPolicy{Role: roleSubject("reader"), Domain: "default", Path: "/article", Method: "GET"}))
+	ups, _, err := ParsePolicyFile(path)
+	assert.NoError(t, err)
+	assert.Len(t, ups, 2)
+
+	// 重试变更返回政策已存在
+	err = c.AddUriPolicy(&reader)
+	assert.False(t, errors.As(err, &notify))
+	assert.Equal(t, ErrorCasbinPolicyExists, err.Error())
+
+	err = c.SaveAllPolicyCsv(ups[:1], nil)
+	assert.True(t, errors.As(err, &notify))
+	assert.Error(t, c.VerifyUriPolicy(&UriPolicy{Role: roleSubject("reader"), Domain: "default", Path: "/article", Method: "GET"}))
+}